@@ -2,10 +2,17 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -13,9 +20,11 @@ import (
 
 	"github.com/renatoathaydes/go-hash/encryption"
 	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/renatoathaydes/go-hash/importer"
 
 	"github.com/atotto/clipboard"
 	"github.com/chzyer/readline"
+	homedir "github.com/mitchellh/go-homedir"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -25,9 +34,13 @@ type State = gohash_db.State
 // LoginInfo local alias
 type LoginInfo = gohash_db.LoginInfo
 
+// CustomField local alias
+type CustomField = gohash_db.CustomField
+
 type command interface {
 	// run a command with the given state, within the given group.
-	run(state *State, group string, args string, reader *bufio.Reader)
+	// Returns true if the command changed the state, or anything else that requires saving the database.
+	run(state *State, group string, args string, reader *bufio.Reader) (changed bool)
 
 	// help returns helpful information about how to use this command.
 	help() string
@@ -48,11 +61,14 @@ type helpCommand struct {
 
 type entryCommand struct {
 	entries func() []string
+	tags    func() []string
+	output  outputOptions
 }
 
 type groupCommand struct {
 	groups   func() []string
 	groupBox *stringBox
+	output   outputOptions
 }
 
 type cpCommand struct {
@@ -63,23 +79,122 @@ type gotoCommand struct {
 	entries func() []string
 }
 
+type noteCommand struct {
+	notes func() []string
+}
+
+type attachCommand struct {
+	entries func() []string
+}
+
+type otpCommand struct {
+	entries func() []string
+	// readOnly whether the database was opened in read-only mode, so HOTP counters can't be saved.
+	readOnly bool
+}
+
+type tagCommand struct {
+	entries func() []string
+	tags    func() []string
+}
+
+type findCommand struct {
+}
+
 type cmpCommand struct {
-	mpBox *stringBox
+	keyBox *sessionKeyBox
+}
+
+type kdfCommand struct {
+	keyBox *sessionKeyBox
+}
+
+type calibrateCommand struct {
+	keyBox *sessionKeyBox
+}
+
+type backupCommand struct {
+	keyBox *sessionKeyBox
+	dbPath string
+}
+
+type saveCommand struct {
+	autosave *bool
+	save     func() bool
+}
+
+// undoCommand undoes, or redoes, the changes made by other commands.
+type undoCommand struct {
+	history  *gohash_db.UndoHistory
+	groupBox *stringBox
+	redo     bool
+}
+
+// trashCommand lists, restores and purges deleted entries and groups.
+type trashCommand struct {
+	// retention how long entries are kept in the trash, or zero to keep them until the trash is emptied.
+	retention time.Duration
+	items     func() []string
+}
+
+// importCommand imports the entries exported by other password managers.
+type importCommand struct {
+	// save saves the database, if it has unsaved changes, before export files are deleted.
+	save func() bool
+	// afterSave is set to a function that must run after the changes made by the command are recorded, and
+	// saved if autosave is on, as it may save the database itself.
+	afterSave *func()
+}
+
+// importOptions the options of the import command.
+type importOptions struct {
+	dryRun bool
+	policy importer.Policy
+	// group the group all entries are imported into, or empty to import entries into their own groups.
+	group string
+}
+
+// outputOptions how groups and entries are listed and shown.
+type outputOptions struct {
+	format gohash_db.OutputFormat
+	// withSecrets whether passwords, the values of secret fields and the contents of notes are included in
+	// JSON and YAML documents.
+	withSecrets bool
 }
 
 type stringBox struct {
 	value string
 }
 
+// sessionKeyBox holds the key derived from the master password of the open database.
+type sessionKeyBox struct {
+	key *gohash_db.SessionKey
+}
+
+// replace the current key with a new one, destroying the current key.
+func (box *sessionKeyBox) replace(key *gohash_db.SessionKey) {
+	if box.key != nil {
+		box.key.Destroy()
+	}
+	box.key = key
+}
+
 // ============= CLI creation ============= //
 
-func createCommands(state *State, groupBox *stringBox, masterPassBox *stringBox) map[string]command {
+func createCommands(state *State, groupBox *stringBox, keyBox *sessionKeyBox, dbPath string,
+	output outputOptions, readOnly bool) map[string]command {
 	getGroups := func() []string {
-		result := make([]string, len(*state), len(*state))
-		i := 0
+		// names of the sub-groups of the current group, then the full paths of all groups
+		var result []string
+		if groupBox.value != gohash_db.DefaultGroup {
+			for _, gr := range state.SubGroups(groupBox.value) {
+				result = append(result, gohash_db.GroupName(gr))
+			}
+		}
 		for gr := range *state {
-			result[i] = gr
-			i++
+			if gr != gohash_db.TrashGroup {
+				result = append(result, gr)
+			}
 		}
 		return result
 	}
@@ -93,13 +208,31 @@ func createCommands(state *State, groupBox *stringBox, masterPassBox *stringBox)
 		return result
 	}
 
+	getTags := func() []string {
+		tags, _ := state.Tags()
+		return tags
+	}
+
+	getNotes := func() []string {
+		var result []string
+		for _, e := range (*state)[groupBox.value] {
+			if e.Kind == gohash_db.NoteKind {
+				result = append(result, e.Name)
+			}
+		}
+		return result
+	}
+
 	var commands = map[string]command{
 		"group": groupCommand{
 			groups:   getGroups,
 			groupBox: groupBox,
+			output:   output,
 		},
 		"entry": entryCommand{
 			entries: getEntries,
+			tags:    getTags,
+			output:  output,
 		},
 		"cp": cpCommand{
 			entries: getEntries,
@@ -107,8 +240,33 @@ func createCommands(state *State, groupBox *stringBox, masterPassBox *stringBox)
 		"goto": gotoCommand{
 			entries: getEntries,
 		},
+		"note": noteCommand{
+			notes: getNotes,
+		},
+		"attach": attachCommand{
+			entries: getEntries,
+		},
+		"otp": otpCommand{
+			entries:  getEntries,
+			readOnly: readOnly,
+		},
+		"tag": tagCommand{
+			entries: getEntries,
+			tags:    getTags,
+		},
+		"find": findCommand{},
 		"cmp": cmpCommand{
-			mpBox: masterPassBox,
+			keyBox: keyBox,
+		},
+		"kdf": kdfCommand{
+			keyBox: keyBox,
+		},
+		"calibrate": calibrateCommand{
+			keyBox: keyBox,
+		},
+		"backup": backupCommand{
+			keyBox: keyBox,
+			dbPath: dbPath,
 		},
 	}
 
@@ -154,10 +312,61 @@ func (cmd gotoCommand) help() string {
 	return "goes to the URL associated with an entry and copies its password to the clipboard."
 }
 
+func (cmd noteCommand) help() string {
+	return "shows, copies and edits multi-line secure notes."
+}
+
+func (cmd attachCommand) help() string {
+	return "adds, lists, extracts and deletes files attached to an entry."
+}
+
+func (cmd otpCommand) help() string {
+	return "generates one-time passwords (2FA codes) for an entry, copying them to the clipboard."
+}
+
+func (cmd tagCommand) help() string {
+	return "adds and removes tags, which can be used to find entries across all groups."
+}
+
+func (cmd findCommand) help() string {
+	return "finds entries in all groups, then shows, copies or goes to one of them."
+}
+
 func (cmd cmpCommand) help() string {
 	return "changes the master password."
 }
 
+func (cmd kdfCommand) help() string {
+	return "shows or changes the parameters used to hash the master password."
+}
+
+func (cmd calibrateCommand) help() string {
+	return "finds the parameters to hash the master password in a given time on this machine."
+}
+
+func (cmd backupCommand) help() string {
+	return "lists, verifies and restores backups of the database."
+}
+
+func (cmd undoCommand) help() string {
+	if cmd.redo {
+		return "redoes the last change that was undone."
+	}
+	return "undoes the last change made to the database in this session."
+}
+
+func (cmd trashCommand) help() string {
+	return "lists, restores and permanently deletes the entries and groups in the trash."
+}
+
+func (cmd importCommand) help() string {
+	return "imports the entries exported by other password managers and browsers, such as KeePass and Chrome."
+}
+
+func (cmd saveCommand) help() string {
+	return "saves the database, or turns autosave on/off."
+}
+
 // ============= Commands: Long help ============= //
 
 const helpUsage = `
@@ -179,18 +388,41 @@ The entry command is used to manage entries within the current group or a specif
 To switch to a different group, use the 'group' command (type 'help group' for more information about groups).
 
 Usage:
-  entry [-option] [[<group>:]<name>]
+  entry [-option] [[<group>:]<name> | #<id>]
+  entry -m [<group>:]<name> | #<id> <target-group>
+  entry -y [<group>:]<name> | #<id> [<target-group>]
+  entry --tag <tag>
+  entry -o <format> [--with-secrets] [[<group>:]<name> | #<id> | --tag <tag>]
 
 Options:
   -c   create an entry.
   -d   delete an entry.
   -e   edit an entry.
+  -h   show the password history of an entry, allowing a previous password to be copied or restored.
+  -m   move an entry to another group.
   -r   rename an entry.
+  -y   duplicate an entry, in the same group or in another group.
+  -o   list or show entries in the given format: table, json or yaml.
 
 Without an option or an argument, the entry command simply lists all entries within the current group.
 
+With the -o option, entries are listed or shown as JSON or YAML documents, which can be read by other tools.
+Passwords, the values of secret fields and the contents of notes are only included with --with-secrets.
+The default format can be chosen when starting go-hash, with the -format flag.
+
+Moved entries keep their ID and timestamps, while duplicated entries are new entries, with a new ID.
+Entries duplicated within their own group are given a new name, e.g. 'gmail (copy)'. If the target group
+already has an entry with the same name, you can choose to rename the moved or duplicated entry, or to
+overwrite the existing entry, which is moved to the trash.
+
+The --tag option lists the entries with the given tag in all groups (type 'help tag' for more information
+about tags).
+
 Typing 'entry <name>' will either display information about the entry, or create it if the entry does not exist.
 
+Every entry has a unique ID, which is shown together with the entry's information. Entries in any group
+can be referred to by the first few characters of their ID, prefixed with '#' (e.g. '#3f2a').
+
 Examples:
 
   # list all entries in the current group
@@ -201,6 +433,21 @@ Examples:
 
   # display the entry called 'foo' in the 'top-secret' group
   entry top-secret:foo
+
+  # edit the entry whose ID starts with '3f2a'
+  entry -e #3f2a
+
+  # move the entry called 'console' in the 'work/aws' group to the 'archive' group
+  entry -m work/aws:console archive
+
+  # duplicate the entry called 'gmail' in the current group
+  entry -y gmail
+
+  # list the entries tagged with 'infra' in all groups
+  entry --tag infra
+
+  # show the entry called 'gmail' as a JSON document, including its password
+  entry -o json --with-secrets gmail
 `
 const groupUsage = `
 === group command usage ===
@@ -208,20 +455,30 @@ const groupUsage = `
 The group command is used to manage groups or enter a group in order to manage its entries.
 
 Usage:
-  group [-option] [<name>]
+  group [-option] [<path>]
+  group -o <format> [--with-secrets]
 
 Options:
-  -c <name>   create a group.
-  -d <name>   delete a group.
-  -r <name>   rename a group.
+  -c <path>   create a group.
+  -d <path>   delete a group, including its sub-groups.
+  -r <path>   rename or move a group, including its sub-groups.
+  -o <format> list the sub-groups and entries of the current group in the given format: table, json or yaml.
+
+Without an option or a <path> argument, the group command lists the sub-groups of the current group
+or, if no group has been entered, all top-level groups.
 
-Without an option or a <name> argument, the group command simply lists all groups in the database.
+Groups may be nested within other groups. The path of a nested group contains the names of all the groups
+it's in, separated by '/', e.g. 'work/aws/prod'. Paths are relative to the current group, unless they
+start with a '/' or the group does not exist within the current group. For example, within the 'work'
+group, 'aws/prod' refers to 'work/aws/prod', and '/personal' refers to the top-level 'personal' group.
 
-Typing 'group <name>' will either enter the group (so that the 'entry' command will apply to entries
+Typing 'group <path>' will either enter the group (so that the 'entry' command will apply to entries
 within the chosen group) , or create it if it does not exist.
 
 After entering a group, the 'entry' command applies only to the entries within the entered group.
-Type 'exit' to exit a group.
+Type 'exit' to exit a group, going back to its parent group. The full path of the current group is shown
+in the prompt. Entries in other groups can be referred to by the full path of their group, followed
+by ':' and the name of the entry, e.g. 'work/aws/prod:console'.
 
 A group called 'default' is used if no group is entered. This group always exists but is not
 shown in the prompt as other groups, allowing the user to manage entries without using groups
@@ -229,9 +486,12 @@ explicitly.
 
 Examples:
 
-  # list all groups
+  # list all groups in the current group
   group
 
+  # enter the 'prod' group, nested within the 'aws' group, within the 'work' group
+  group work/aws/prod
+
   # delete a group called 'hello'
   group -d hello
 `
@@ -243,11 +503,12 @@ The cp command can be used to copy information about entries to the clipboard.
 That allows users to easily copy/paste the information where the information is required.
 
 Usage:
-  cp [-option] [<group>:]<name>
+  cp [-option] [<group>:]<name> | #<id>
 
 Options:
-  -u   copy the username.
-  -p   copy the password.
+  -u           copy the username.
+  -p           copy the password.
+  -f <field>   copy the value of a field: url, description, note, id, or the name of a custom field.
 
 If an option is not provided, the username associated with the chosen entry is copied.
 Information is automatically removed from the clipboard after one minute.
@@ -259,6 +520,9 @@ Examples:
 
   # copy the password associated with the 'other' entry
   cp -p other
+
+  # copy the custom field called 'pin' of the 'bank' entry
+  cp -f pin bank
 `
 
 const gotoUsage = `
@@ -269,7 +533,7 @@ an entry directly in the default browser, then copying the password to the clipb
 it can be pasted into the login form without waste of time.
 
 Usage:
-  goto [-option] [<group>:]<name>
+  goto [-option] [<group>:]<name> | #<id>
 
 Options:
   -n   do not copy the password.
@@ -282,6 +546,158 @@ Examples:
   goto hello
 `
 
+const noteUsage = `
+=== note command usage ===
+
+The note command manages secure notes, which are entries holding multi-line text, such as
+recovery codes, configuration snippets or license keys, instead of login information.
+
+Usage:
+  note [-option] [[<group>:]<name> | #<id>]
+
+Options:
+  -c    create a note.
+  -e    edit a note.
+  -cp   copy the full contents of a note to the clipboard.
+
+Without an option or an argument, the note command lists all notes within the current group.
+
+Typing 'note <name>' will either display the full contents of the note, or create it if it does not exist.
+
+The contents of a note are entered line by line, finishing with a line containing only a single '.'.
+If the EDITOR environment variable is set, the note may be written with that editor instead. The note is
+then written temporarily to a file in a memory-backed location (e.g. /dev/shm) where possible, and the
+file is wiped and removed as soon as the editor exits.
+
+Notes are also listed by the 'entry' command, which shows only how many lines they have, and can be
+renamed and deleted like any other entry.
+
+Examples:
+
+  # create a note called 'recovery-codes' in the current group
+  note -c recovery-codes
+
+  # copy the contents of the 'ssh-config' note to the clipboard
+  note -cp ssh-config
+`
+
+const attachUsage = `
+=== attach command usage ===
+
+The attach command manages small files, such as SSH private keys or recovery documents, attached to an entry.
+Attachments are stored inside the database, so they are encrypted together with all other information.
+
+Usage:
+  attach [-option [<attachment>]] [<group>:]<name> | #<id>
+
+Options:
+  -a                add a file to the entry.
+  -x <attachment>   extract an attachment to a file.
+  -d <attachment>   delete an attachment.
+
+Without an option, the attach command lists the attachments of the entry.
+
+When adding a file, you're asked for the path of the file and the name of the attachment, which defaults to
+the name of the file. As the whole database is kept in memory, the total size of the database is limited,
+so only small files can be attached.
+
+When extracting an attachment, you're asked for the path of the file to write, which defaults to the name
+of the attachment in the current directory. The file is only readable and writable by the current user.
+
+Examples:
+
+  # attach a file to the 'server' entry
+  attach -a server
+
+  # extract the attachment called 'id_rsa' of the 'server' entry
+  attach -x id_rsa server
+`
+
+const otpUsage = `
+=== otp command usage ===
+
+The otp command generates one-time passwords, as used for two-factor authentication, and copies them
+to the clipboard.
+
+Usage:
+  otp [-option] [<group>:]<name> | #<id>
+
+Options:
+  -s   set up one-time passwords for the entry.
+  -d   remove one-time passwords from the entry.
+  -u   show the otpauth URI of the entry, which can be used to set up one-time passwords in another app.
+
+Without an option, the otp command shows the current one-time password of the entry, and how long it
+remains valid for, and copies it to the clipboard.
+
+To set up one-time passwords, enter either the otpauth URI (the contents of the QR code shown by websites
+when setting up two-factor authentication), or the base32-encoded secret, in which case the most common
+parameters are used (time-based codes with 6 digits, renewed every 30 seconds, using SHA1).
+
+Both time-based (TOTP) and counter-based (HOTP) one-time passwords are supported.
+
+Examples:
+
+  # set up one-time passwords for the 'github' entry
+  otp -s github
+
+  # copy the current one-time password of the 'github' entry
+  otp github
+`
+
+const tagUsage = `
+=== tag command usage ===
+
+The tag command adds and removes tags. Tags are labels which can be given to any number of entries,
+in any group, so that related entries can be found together regardless of their group.
+
+Usage:
+  tag [-option <tag>[,<tag>...]] [[<group>:]<name> | #<id>]
+
+Options:
+  -a <tags>   add one or more tags, separated by commas, to an entry.
+  -d <tags>   remove one or more tags, separated by commas, from an entry.
+
+Without an option or an argument, the tag command lists all tags in use, and how many entries have each tag.
+Typing 'tag <name>' shows the tags of an entry.
+
+Tags are case-insensitive and cannot contain spaces or commas.
+To list all entries with a given tag, type 'entry --tag <tag>'.
+
+Examples:
+
+  # tag the 'aws' entry with 'work' and 'infra'
+  tag -a work,infra aws
+
+  # remove the 'infra' tag from the 'aws' entry
+  tag -d infra aws
+`
+
+const findUsage = `
+=== find command usage ===
+
+The find command searches all groups for entries matching a query, listing the best matches first.
+
+Usage:
+  find <query>
+
+The name, URL host, username, description and tags of entries are searched, ignoring case.
+If the query contains several words, all of them must match. Words don't need to match exactly,
+so 'gthb' finds 'github', for example, but exact matches, and matches in the name of the entry,
+are listed first.
+
+After the results are listed, enter the number of a result to show the entry, copy its password
+or username, or go to its URL.
+
+Examples:
+
+  # find entries related to AWS
+  find aws
+
+  # find entries matching both 'joe' and 'google'
+  find joe google
+`
+
 const cmpUsage = `
 === cmp command usage ===
 
@@ -290,6 +706,229 @@ The cmp command is used to change the master password.
 No options or arguments are accepted.
 `
 
+const kdfUsage = `
+=== kdf command usage ===
+
+The kdf command shows or changes the parameters of the key derivation function (KDF) used to
+hash the master password. The parameters are stored in the database, so each database may
+use different parameters.
+
+Higher time and memory parameters make it harder for an attacker to guess the master password,
+but also make it slower to open and save the database. The time parameter can be at most 64, the memory
+parameter at most 2048 MiB, and the time multiplied by the memory at most 16384 MiB.
+
+Usage:
+  kdf [-a <function>] [-t <time>] [-m <memory>] [-p <threads>] [-s <salt length>]
+
+Options:
+  -a   the key derivation function (argon2id or argon2i).
+  -t   the time parameter (number of iterations).
+  -m   the memory parameter, in MiB.
+  -p   the parallelism parameter (number of threads).
+  -s   the length of the salt, in bytes.
+
+Without options, the kdf command shows the current parameters.
+When any option is given, go-hash asks for the master password, then re-encrypts the
+database using the new parameters. Parameters which are not given keep their current values.
+
+Examples:
+
+  # show the current parameters
+  kdf
+
+  # use 16 iterations and 256 MiB of memory
+  kdf -t 16 -m 256
+`
+
+const calibrateUsage = `
+=== calibrate command usage ===
+
+The calibrate command benchmarks the key derivation function (Argon2id) on this machine to find
+parameters which make unlocking the database take approximately the given amount of time.
+
+As much memory as allowed is used, as that makes it harder for attackers using specialized hardware
+to guess the master password. The time parameter is then chosen to reach the target time.
+
+Usage:
+  calibrate [-t <target time>] [-m <maximum memory>]
+
+Options:
+  -t   the target unlock time (default: 1s).
+  -m   the maximum memory to use, in MiB (default: 256).
+
+The proposed parameters are shown, and can then be applied to the database, which is re-encrypted after
+the master password is confirmed. Type 'help kdf' for more information about the parameters.
+
+Examples:
+
+  # find parameters to unlock the database in about 1 second
+  calibrate
+
+  # find parameters to unlock the database in about 2.5 seconds, using at most 1 GiB of memory
+  calibrate -t 2.5s -m 1024
+`
+
+const backupUsage = `
+=== backup command usage ===
+
+Every time the database is saved, go-hash keeps the previous version of the database file as a backup.
+The most recent backup is called <database file>.bak.1, the one before it <database file>.bak.2, and so on.
+The number of backups to keep is set with the -backups flag when starting go-hash (3 by default).
+
+The backup command is used to list, verify and restore these backups.
+
+Usage:
+  backup [-option] [<number>]
+
+Options:
+  -v [<number>]   verify that a backup (or all backups) can be opened with the master password.
+  -r <number>     restore a backup.
+
+Without an option, the backup command simply lists all backups.
+
+Restoring a backup replaces the current entries and groups with the ones in the backup.
+The database is then saved with the current master password, so the current version of the
+database file becomes the most recent backup.
+
+Examples:
+
+  # list all backups
+  backup
+
+  # restore the most recent backup
+  backup -r 1
+`
+
+const saveUsage = `
+=== save command usage ===
+
+The save command saves the database.
+
+By default, go-hash saves the database automatically after every command that makes changes to it.
+If autosave is turned off, the prompt shows a '*' when there are unsaved changes, and the database
+is only saved when the save command is used (go-hash offers to save any unsaved changes on quitting).
+
+Usage:
+  save [-a on|off]
+
+Options:
+  -a   turns autosave on or off.
+
+Without an option, the save command saves the database.
+
+Autosave can also be turned off when starting go-hash with the -autosave=false flag.
+
+Examples:
+
+  # turn autosave off
+  save -a off
+`
+
+const undoUsage = `
+=== undo and redo commands usage ===
+
+The undo command reverts the last change made to the database in the current session, such as deleting
+a group or editing an entry. The redo command re-applies the last change that was undone.
+
+Usage:
+  undo
+  redo
+
+Before a change is undone or redone, go-hash shows the command that made the change, and what will change,
+asking for confirmation. Changes can be undone one by one, up to the first change of the session.
+Making a new change after undoing others discards the changes that could be redone.
+
+The history of changes only exists in memory, and it's lost when go-hash exits. It's also cleared when
+changes made to the database file by another process are merged into the session.
+
+Undoing a change does not restore any previous versions of the database file, see 'help backup' for that.
+`
+
+const trashUsage = `
+=== trash command usage ===
+
+Deleted entries and groups are moved to the trash, from where they can be restored until they are purged.
+
+Usage:
+  trash [-option [<number>]]
+
+Options:
+  -r <number>   restore an item, i.e. an entry or a group with all of its entries.
+  -p <number>   permanently delete an item.
+  -e            empty the trash, permanently deleting all items.
+
+Without an option, the trash command lists the items in the trash, most recently deleted first.
+
+Restored entries go back to the group they were deleted from, which is re-created if necessary.
+If the group already has an entry with the same name, the restored entry is renamed, e.g. to 'gmail (copy)'.
+
+Items are purged automatically when go-hash starts, once they've been in the trash for longer than the
+retention period, 30 days by default. The retention period can be changed with the -trash flag when
+starting go-hash (use -trash 0 to keep items until the trash is emptied).
+
+Examples:
+
+  # list the items in the trash
+  trash
+
+  # restore the most recently deleted item
+  trash -r 1
+`
+
+const importUsage = `
+=== import command usage ===
+
+The import command adds the entries exported by other password managers to the database.
+
+Usage:
+  import [-n] [-c <policy>] [-g <group>] <source> <file>
+
+Options:
+  -n            dry run: show what would be imported, without changing the database.
+  -c <policy>   what to do with entries whose names are already taken in their groups:
+                  skip        do not import the entry (the default).
+                  rename      import the entry with a new name, e.g. 'gmail (copy)'.
+                  overwrite   replace the existing entry, which is moved to the trash.
+  -g <group>    import all entries into the given group (the full path of the group).
+
+Sources:
+  keepass     a KeePass 2 XML export, or a KeePassXC CSV export.
+  bitwarden   an unencrypted Bitwarden JSON export.
+  1password   a 1Password 1PUX export, or a 1Password CSV export.
+  browser     a CSV export of the passwords saved by Chrome, Firefox and other browsers.
+
+Groups, folders and vaults are imported as groups, with the same names (':' and '/' are replaced with '-',
+except for Bitwarden's nested folders), and entries are imported into their groups. The title, username,
+password, URL and notes of entries are imported as the name, username, password, URL and description of
+go-hash entries, and the time entries were last modified is kept. Entries without a title are named after the
+host of their URL, and entries with only notes are imported as notes (see 'help note'). Other values are
+imported as custom fields, except for one-time password settings, which are imported as such (see 'help otp').
+CSV exports of 1Password do not include vaults, so their entries are imported into the default group.
+
+Browsers do not group passwords, so their entries are imported into groups named after the domain of their
+URL, e.g. an entry for https://accounts.google.com is imported into the group 'google.com', unless the -g
+option is used. Entries are named after the host of their URL, followed by their username if there are
+several entries for the same host, e.g. 'accounts.google.com (joe)'.
+
+Entries whose names are already taken, and anything that could not be imported, such as entries in the
+recycle bin or archived items, are listed after the import.
+
+Export files are not encrypted, so after entries are imported, go-hash offers to save the database, then
+overwrite the export file with random bytes and delete it. Some disks and file systems, such as SSDs, may
+still keep copies of the contents of the file, which only full-disk encryption protects.
+
+Examples:
+
+  # import a KeePassXC CSV export
+  import keepass ~/Downloads/passwords.csv
+
+  # show what would be imported from a Bitwarden export, renaming entries whose names are taken
+  import -n -c rename bitwarden ~/Downloads/bitwarden_export.json
+
+  # import the passwords exported by Chrome into the group 'web'
+  import -g web browser ~/Downloads/Chrome Passwords.csv
+`
+
 func (cmd helpCommand) longHelp() string {
 	return helpUsage
 }
@@ -310,16 +949,64 @@ func (cmd gotoCommand) longHelp() string {
 	return gotoUsage
 }
 
-func (cmd cmpCommand) longHelp() string {
-	return cmpUsage
+func (cmd noteCommand) longHelp() string {
+	return noteUsage
 }
 
-// ============= Commands: Auto-completers ============= //
+func (cmd attachCommand) longHelp() string {
+	return attachUsage
+}
 
-func (cmd helpCommand) completer() readline.PrefixCompleterInterface {
-	commands := cmd.commands
-	commandItems := make([]readline.PrefixCompleterInterface, len(commands), len(commands))
-	i := 0
+func (cmd otpCommand) longHelp() string {
+	return otpUsage
+}
+
+func (cmd tagCommand) longHelp() string {
+	return tagUsage
+}
+
+func (cmd findCommand) longHelp() string {
+	return findUsage
+}
+
+func (cmd cmpCommand) longHelp() string {
+	return cmpUsage
+}
+
+func (cmd kdfCommand) longHelp() string {
+	return kdfUsage
+}
+
+func (cmd calibrateCommand) longHelp() string {
+	return calibrateUsage
+}
+
+func (cmd backupCommand) longHelp() string {
+	return backupUsage
+}
+
+func (cmd undoCommand) longHelp() string {
+	return undoUsage
+}
+
+func (cmd trashCommand) longHelp() string {
+	return trashUsage
+}
+
+func (cmd importCommand) longHelp() string {
+	return importUsage
+}
+
+func (cmd saveCommand) longHelp() string {
+	return saveUsage
+}
+
+// ============= Commands: Auto-completers ============= //
+
+func (cmd helpCommand) completer() readline.PrefixCompleterInterface {
+	commands := cmd.commands
+	commandItems := make([]readline.PrefixCompleterInterface, len(commands), len(commands))
+	i := 0
 	for name := range commands {
 		commandItems[i] = readline.PcItem(name)
 		i++
@@ -341,7 +1028,12 @@ func (cmd entryCommand) completer() readline.PrefixCompleterInterface {
 		readline.PcItem("-c"),
 		readline.PcItem("-d", cmp),
 		readline.PcItem("-e", cmp),
-		readline.PcItem("-r", cmp))
+		readline.PcItem("-h", cmp),
+		readline.PcItem("-m", cmp),
+		readline.PcItem("-r", cmp),
+		readline.PcItem("-y", cmp),
+		readline.PcItem("--tag", commandCompleter(cmd.tags)),
+		outputOptionsCompleter(cmp))
 }
 
 func (cmd groupCommand) completer() readline.PrefixCompleterInterface {
@@ -350,7 +1042,17 @@ func (cmd groupCommand) completer() readline.PrefixCompleterInterface {
 		cmp,
 		readline.PcItem("-c"),
 		readline.PcItem("-d", cmp),
-		readline.PcItem("-r", cmp))
+		readline.PcItem("-r", cmp),
+		outputOptionsCompleter())
+}
+
+// outputOptionsCompleter completes the '-o <format>' option, followed by the given items.
+func outputOptionsCompleter(items ...readline.PrefixCompleterInterface) readline.PrefixCompleterInterface {
+	afterFormat := append([]readline.PrefixCompleterInterface{readline.PcItem("--with-secrets", items...)}, items...)
+	return readline.PcItem("-o",
+		readline.PcItem(gohash_db.TableFormat.String(), afterFormat...),
+		readline.PcItem(gohash_db.JSONFormat.String(), afterFormat...),
+		readline.PcItem(gohash_db.YAMLFormat.String(), afterFormat...))
 }
 
 func (cmd cpCommand) completer() readline.PrefixCompleterInterface {
@@ -358,7 +1060,8 @@ func (cmd cpCommand) completer() readline.PrefixCompleterInterface {
 	return readline.PcItem("cp",
 		cmp,
 		readline.PcItem("-u", cmp),
-		readline.PcItem("-p", cmp))
+		readline.PcItem("-p", cmp),
+		readline.PcItem("-f"))
 }
 
 func (cmd gotoCommand) completer() readline.PrefixCompleterInterface {
@@ -368,10 +1071,106 @@ func (cmd gotoCommand) completer() readline.PrefixCompleterInterface {
 		readline.PcItem("-n", cmp))
 }
 
+func (cmd noteCommand) completer() readline.PrefixCompleterInterface {
+	cmp := commandCompleter(cmd.notes)
+	return readline.PcItem("note",
+		cmp,
+		readline.PcItem("-c"),
+		readline.PcItem("-e", cmp),
+		readline.PcItem("-cp", cmp))
+}
+
+func (cmd attachCommand) completer() readline.PrefixCompleterInterface {
+	cmp := commandCompleter(cmd.entries)
+	return readline.PcItem("attach",
+		cmp,
+		readline.PcItem("-a", cmp),
+		readline.PcItem("-x"),
+		readline.PcItem("-d"))
+}
+
+func (cmd otpCommand) completer() readline.PrefixCompleterInterface {
+	cmp := commandCompleter(cmd.entries)
+	return readline.PcItem("otp",
+		cmp,
+		readline.PcItem("-s", cmp),
+		readline.PcItem("-d", cmp),
+		readline.PcItem("-u", cmp))
+}
+
+func (cmd tagCommand) completer() readline.PrefixCompleterInterface {
+	cmp := commandCompleter(cmd.entries)
+	tagsThenEntries := func() readline.PrefixCompleterInterface {
+		return readline.PcItemDynamic(func(line string) []string {
+			return cmd.tags()
+		}, cmp)
+	}
+	return readline.PcItem("tag",
+		cmp,
+		readline.PcItem("-a", tagsThenEntries()),
+		readline.PcItem("-d", tagsThenEntries()))
+}
+
+func (cmd findCommand) completer() readline.PrefixCompleterInterface {
+	return readline.PcItem("find")
+}
+
 func (cmd cmpCommand) completer() readline.PrefixCompleterInterface {
 	return readline.PcItem("cmp")
 }
 
+func (cmd kdfCommand) completer() readline.PrefixCompleterInterface {
+	return readline.PcItem("kdf",
+		readline.PcItem("-a", readline.PcItem("argon2id"), readline.PcItem("argon2i")),
+		readline.PcItem("-t"),
+		readline.PcItem("-m"),
+		readline.PcItem("-p"),
+		readline.PcItem("-s"))
+}
+
+func (cmd calibrateCommand) completer() readline.PrefixCompleterInterface {
+	return readline.PcItem("calibrate",
+		readline.PcItem("-t"),
+		readline.PcItem("-m"))
+}
+
+func (cmd undoCommand) completer() readline.PrefixCompleterInterface {
+	if cmd.redo {
+		return readline.PcItem("redo")
+	}
+	return readline.PcItem("undo")
+}
+
+func (cmd trashCommand) completer() readline.PrefixCompleterInterface {
+	cmp := commandCompleter(cmd.items)
+	return readline.PcItem("trash",
+		readline.PcItem("-r", cmp),
+		readline.PcItem("-p", cmp),
+		readline.PcItem("-e"))
+}
+
+func (cmd importCommand) completer() readline.PrefixCompleterInterface {
+	var sources []readline.PrefixCompleterInterface
+	for _, source := range importSources() {
+		sources = append(sources, readline.PcItem(source))
+	}
+	policies := readline.PcItem("-c", readline.PcItem("skip"), readline.PcItem("rename"),
+		readline.PcItem("overwrite"))
+	return readline.PcItem("import", append(sources, readline.PcItem("-n"), policies, readline.PcItem("-g"))...)
+}
+
+func (cmd saveCommand) completer() readline.PrefixCompleterInterface {
+	return readline.PcItem("save",
+		readline.PcItem("-a", readline.PcItem("on"), readline.PcItem("off")))
+}
+
+func (cmd backupCommand) completer() readline.PrefixCompleterInterface {
+	cmp := commandCompleter(cmd.backupNumbers)
+	return readline.PcItem("backup",
+		readline.PcItem("-v", cmp),
+		readline.PcItem("-r", cmp))
+}
+
 // ============= Commands: requires password after idle timeout ============= //
 
 func (cmd helpCommand) requiresPasswordIfIdleTooLong() bool {
@@ -394,13 +1193,61 @@ func (cmd gotoCommand) requiresPasswordIfIdleTooLong() bool {
 	return true
 }
 
+func (cmd noteCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd attachCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd otpCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd tagCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd findCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
 func (cmd cmpCommand) requiresPasswordIfIdleTooLong() bool {
 	return false // it will ask for the password in the implementation
 }
 
+func (cmd kdfCommand) requiresPasswordIfIdleTooLong() bool {
+	return false // it will ask for the password in the implementation
+}
+
+func (cmd calibrateCommand) requiresPasswordIfIdleTooLong() bool {
+	return false // it will ask for the password in the implementation
+}
+
+func (cmd backupCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd undoCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd trashCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd importCommand) requiresPasswordIfIdleTooLong() bool {
+	return true
+}
+
+func (cmd saveCommand) requiresPasswordIfIdleTooLong() bool {
+	return false
+}
+
 // ============= Commands: run implementations ============= //
 
-func (cmd helpCommand) run(state *State, group, args string, reader *bufio.Reader) {
+func (cmd helpCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
 	commands := cmd.commands
 	if args == "" {
 		println("go-hash commands:\n")
@@ -417,17 +1264,29 @@ func (cmd helpCommand) run(state *State, group, args string, reader *bufio.Reade
 			println("Error: command does not exist.")
 		}
 	}
+	return false
 }
 
-func (cmd entryCommand) run(state *State, group, args string, reader *bufio.Reader) {
+func (cmd entryCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
 	var (
 		CreateEntry bool
 		DeleteEntry bool
 		RenameEntry bool
 		EditEntry   bool
+		ShowHistory bool
+		MoveEntry   bool
+		CopyEntry   bool
+		ListTag     bool
 		entry       string
 	)
+	output, args, ok := parseOutputOptions(args, cmd.output)
+	if !ok {
+		return
+	}
 	switch {
+	case strings.HasPrefix(args, "--tag"):
+		ListTag = true
+		entry = strings.TrimSpace(args[5:])
 	case strings.HasPrefix(args, "-c"):
 		CreateEntry = true
 		entry = strings.TrimSpace(args[2:])
@@ -440,6 +1299,15 @@ func (cmd entryCommand) run(state *State, group, args string, reader *bufio.Read
 	case strings.HasPrefix(args, "-e"):
 		EditEntry = true
 		entry = strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-h"):
+		ShowHistory = true
+		entry = strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-m"):
+		MoveEntry = true
+		entry = strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-y"):
+		CopyEntry = true
+		entry = strings.TrimSpace(args[2:])
 	case strings.HasPrefix(args, "-"):
 		println("Error: unknown option. Type 'help entry' for usage.")
 		return
@@ -449,20 +1317,32 @@ func (cmd entryCommand) run(state *State, group, args string, reader *bufio.Read
 
 	switch {
 	case CreateEntry:
-		createOrShowEntry(entry, state, group, reader, true)
+		changed = createOrShowEntry(entry, state, group, reader, true)
 	case DeleteEntry:
-		removeEntry(entry, state, group, reader)
+		changed = removeEntry(entry, state, group, reader)
 	case RenameEntry:
-		renameEntry(entry, state, group, reader)
+		changed = renameEntry(entry, state, group, reader)
 	case EditEntry:
-		editEntry(entry, state, group, reader)
+		changed = editEntry(entry, state, group, reader)
+	case ShowHistory:
+		changed = showPasswordHistory(entry, state, group, reader)
+	case MoveEntry:
+		changed = moveOrCopyEntry(entry, state, group, reader, true)
+	case CopyEntry:
+		changed = moveOrCopyEntry(entry, state, group, reader, false)
+	case ListTag:
+		listEntriesWithTag(entry, state, output)
 
 	// no option provided, the next cases list or offer to create an entry
+	case len(entry) > 0 && output.format != gohash_db.TableFormat:
+		writeEntryDocument(entry, state, group, output)
 	case len(entry) > 0:
-		createOrShowEntry(entry, state, group, reader, false)
+		changed = createOrShowEntry(entry, state, group, reader, false)
+	case output.format != gohash_db.TableFormat:
+		writeDocument(output.format, state.NewListing(group, output.withSecrets))
 	default:
 		entries := (*state)[group]
-		fmt.Printf("Showing group %s:\n\n", groupDescription(group, &entries, false))
+		fmt.Printf("Showing group %s:\n\n", groupDescription(group, state, false))
 		if len(entries) > 0 {
 			for _, e := range entries {
 				println(e.String())
@@ -470,15 +1350,20 @@ func (cmd entryCommand) run(state *State, group, args string, reader *bufio.Read
 		}
 		println("\nHint: To show the details of a single entry, type 'entry <name>'.")
 	}
+	return
 }
 
-func (cmd groupCommand) run(state *State, group, args string, reader *bufio.Reader) {
+func (cmd groupCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
 	var (
 		CreateGroup bool
 		DeleteGroup bool
 		RenameGroup bool
 		groupName   string
 	)
+	output, args, ok := parseOutputOptions(args, cmd.output)
+	if !ok {
+		return
+	}
 	switch {
 	case strings.HasPrefix(args, "-c"):
 		CreateGroup = true
@@ -498,56 +1383,80 @@ func (cmd groupCommand) run(state *State, group, args string, reader *bufio.Read
 
 	switch {
 	case CreateGroup:
-		cmd.groupBox.value = createGroup(groupName, state, group, reader)
+		if path, exists, ok := resolveGroup(groupName, state, group); ok {
+			if exists {
+				println("Error: group already exists.")
+			} else {
+				cmd.groupBox.value, changed = createGroup(path, state, group)
+			}
+		}
 	case DeleteGroup:
-		cmd.groupBox.value = removeGroup(groupName, state, group, reader)
+		cmd.groupBox.value, changed = removeGroup(groupName, state, group, reader)
 	case RenameGroup:
-		cmd.groupBox.value = renameGroup(groupName, state, group, reader)
+		cmd.groupBox.value, changed = renameGroup(groupName, state, group, reader)
 
 	// no option selected, list or offer to create group
 	case len(groupName) > 0:
-		_, groupExists := (*state)[groupName]
+		path, groupExists, ok := resolveGroup(groupName, state, group)
+		if !ok {
+			return
+		}
 		if groupExists {
-			cmd.groupBox.value = groupName
+			cmd.groupBox.value = path
 		} else {
-			newGroupWanted := yesNoQuestion("Group does not exist, do you want to create it?", reader, true)
+			newGroupWanted := yesNoQuestion("Group '"+path+"' does not exist, do you want to create it?", reader, true)
 			if newGroupWanted {
-				cmd.groupBox.value = createGroup(groupName, state, group, reader)
+				cmd.groupBox.value, changed = createGroup(path, state, group)
 			}
 		}
+	case output.format != gohash_db.TableFormat:
+		writeDocument(output.format, state.NewListing(group, output.withSecrets))
 	default:
-		groupLen := len(*state)
-		switch groupLen {
+		subGroups := state.SubGroups(group)
+		var location string
+		if group != gohash_db.DefaultGroup {
+			location = " in group " + group
+		}
+		switch len(subGroups) {
+		case 0:
+			fmt.Printf("There are no groups%s.\n", location)
 		case 1:
-			println("There is 1 group:\n")
+			fmt.Printf("There is 1 group%s:\n\n", location)
 		default:
-			fmt.Printf("There are %d groups:\n\n", groupLen)
+			fmt.Printf("There are %d groups%s:\n\n", len(subGroups), location)
 		}
-		for groupName, entries := range *state {
-			fmt.Printf("  %s\n", groupDescription(groupName, &entries, true))
+		for _, subGroup := range subGroups {
+			fmt.Printf("  %s\n", groupDescription(subGroup, state, true))
 		}
 		println("\nHint: Type 'entry' to list all entries in the current group.")
 	}
+	return
 }
 
-func (cmd cpCommand) run(state *State, group, args string, reader *bufio.Reader) {
-	CopyPassword := false
-	CopyUsername := false
+func (cmd cpCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
 	entries := (*state)[group]
-	var entry string
+	var entry, fieldName string
 	switch {
 	case strings.HasPrefix(args, "-p"):
-		CopyPassword = true
+		fieldName = "password"
 		entry = strings.TrimSpace(args[2:])
 	case strings.HasPrefix(args, "-u"):
-		CopyUsername = true
+		fieldName = "username"
 		entry = strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-f"):
+		parts := splitTrimN(strings.TrimSpace(args[2:]), 2)
+		fieldName, entry = parts[0], parts[1]
+		if len(entry) == 0 {
+			println("Error: please provide the name of the field and of the entry.")
+			println("Hint: to copy the field 'pin' of the entry 'bank', type 'cp -f pin bank'.")
+			return
+		}
 	case strings.HasPrefix(args, "-"):
 		println("Error: Unknown option.")
-		println("Hint: valid options are: -p (password), -u (username)")
+		println("Hint: valid options are: -p (password), -u (username), -f <field> (any field)")
 		return
 	default:
-		CopyUsername = true
+		fieldName = "username"
 		entry = args
 	}
 
@@ -572,24 +1481,13 @@ func (cmd cpCommand) run(state *State, group, args string, reader *bufio.Reader)
 		println("Error: please provide an entry name.")
 		showEntryHint()
 	} else {
-		entryIndex, found := findEntryIndex(&entries, entry)
-		if !found && strings.Contains(entry, ":") {
-			// split up group:entry from user input
-			parts := strings.SplitN(entry, ":", 2)
-			group = parts[0]
-			entry = parts[1]
-			entries = (*state)[group]
-			entryIndex, found = findEntryIndex(&entries, entry)
-		}
-		if found {
-			var content string
-			switch {
-			case CopyPassword:
-				content = entries[entryIndex].Password
-			case CopyUsername:
-				content = entries[entryIndex].Username
-			default:
-				panic("Unexpected field case")
+		entryGroup, entryIndex, err := findEntry(state, group, entry)
+		if err == nil {
+			info := &(*state)[entryGroup][entryIndex]
+			content, ok := entryField(info, fieldName)
+			if !ok {
+				fmt.Printf("Error: entry '%s' does not have a field called '%s'.\n", info.Name, fieldName)
+				return
 			}
 			err := clipboard.WriteAll(content)
 			if err != nil {
@@ -597,14 +1495,17 @@ func (cmd cpCommand) run(state *State, group, args string, reader *bufio.Reader)
 			} else {
 				go removeFromClipboardAfterDelay(content)
 			}
-		} else {
+		} else if err == gohash_db.ErrEntryNotFound {
 			fmt.Printf("Error: entry '%s' does not exist.\n", entry)
 			showEntryHint()
+		} else {
+			fmt.Printf("Error: %s.\n", err.Error())
 		}
 	}
+	return false
 }
 
-func (cmd gotoCommand) run(state *State, group, args string, reader *bufio.Reader) {
+func (cmd gotoCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
 	entry := args
 	doCopyPass := true
 	if strings.HasPrefix(args, "-n ") {
@@ -615,20 +1516,10 @@ func (cmd gotoCommand) run(state *State, group, args string, reader *bufio.Reade
 		return
 	}
 
-	entries := (*state)[group]
-
-	entryIndex, found := findEntryIndex(&entries, entry)
-	if !found && strings.Contains(entry, ":") {
-		// split up group:entry from user input
-		parts := strings.SplitN(entry, ":", 2)
-		group = parts[0]
-		entry = parts[1]
-		entries = (*state)[group]
-		entryIndex, found = findEntryIndex(&entries, entry)
-	}
+	entryGroup, entryIndex, err := findEntry(state, group, entry)
 
-	if found {
-		URL := entries[entryIndex].URL
+	if err == nil {
+		URL := (*state)[entryGroup][entryIndex].URL
 		if len(URL) == 0 {
 			println("Error: entry does not have a URL to go to.")
 		} else {
@@ -637,162 +1528,1262 @@ func (cmd gotoCommand) run(state *State, group, args string, reader *bufio.Reade
 				cpCommand{}.run(state, group, "-p "+entry, reader)
 			}
 		}
-	} else {
+	} else if err == gohash_db.ErrEntryNotFound {
 		fmt.Printf("Error: entry '%s' does not exist.\n", entry)
+	} else {
+		fmt.Printf("Error: %s.\n", err.Error())
 	}
+	return false
 }
 
-func (cmd cmpCommand) run(state *State, group, args string, reader *bufio.Reader) {
-	if len(args) > 0 {
-		println("Error: the cmp command does not accept any arguments.")
-	} else {
-		attempts := 5
-		for {
-			print("Current password: ")
-			pass, err := terminal.ReadPassword(int(syscall.Stdin))
-			println("")
-			if err != nil {
-				panic(err)
-			}
-			if string(pass) == cmd.mpBox.value {
-				cmd.mpBox.value = createPassword()
-				break
-			} else if attempts == 0 {
-				panic("Too many failed attempts.")
+func (cmd noteCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	var note string
+	switch {
+	// -cp must be checked before -c
+	case strings.HasPrefix(args, "-cp"):
+		note = strings.TrimSpace(args[3:])
+		if entryGroup, entryIndex, ok := findNote(state, group, note); ok {
+			content := (*state)[entryGroup][entryIndex].Note
+			if err := clipboard.WriteAll(content); err != nil {
+				fmt.Printf("Error: unable to copy! Reason: %s\n", err.Error())
 			} else {
-				println("Error: incorrect password. Please try again.")
+				go removeFromClipboardAfterDelay(content)
 			}
-			attempts--
 		}
-	}
-}
-
-// ============= Entry helper functions ============= //
-
-func createOrShowEntry(entry string, state *State, group string,
-	reader *bufio.Reader, createOnly bool) {
-	currentGroup := group
-	if len(entry) > 0 {
-		entries, _ := (*state)[group]
-		if strings.Contains(entry, ":") {
-			// split up group:entry from user input
-			parts := strings.SplitN(entry, ":", 2)
-			candidateGroup := parts[0]
-			candidateEntry := parts[1]
-			useCandidates := ask2OptionsQuestion(
-				"Option 1: create or view entry '"+candidateEntry+"' in group '"+candidateGroup+"'.\n"+
-					"Option 2: create or view entry '"+entry+"' in group '"+group+"'.\n\n"+
-					"Which option do you prefer?", reader, "1", "2", true)
-			if useCandidates {
-				group = candidateGroup
-				entry = candidateEntry
-				var groupExists bool
-				entries, groupExists = (*state)[group]
-				if !groupExists {
-					newGroupWanted := yesNoQuestion("Group does not exist, do you want to create it?", reader, true)
-					if newGroupWanted {
-						createGroup(group, state, group, reader)
-					} else {
-						return
-					}
-				}
-			}
+	case strings.HasPrefix(args, "-c"):
+		changed = createNote(strings.TrimSpace(args[2:]), state, group, reader)
+	case strings.HasPrefix(args, "-e"):
+		note = strings.TrimSpace(args[2:])
+		if entryGroup, entryIndex, ok := findNote(state, group, note); ok {
+			changed = editNote(&(*state)[entryGroup][entryIndex], reader)
 		}
-
-		entryIndex, exists := findEntryIndex(&entries, entry)
-		if exists {
-			if createOnly {
-				println("Error: entry already exists.")
-			} else {
-				println(entries[entryIndex].String())
+	case strings.HasPrefix(args, "-"):
+		println("Error: unknown option. Type 'help note' for usage.")
+	case len(args) > 0:
+		entryGroup, entryIndex, err := findEntry(state, group, args)
+		switch {
+		case err == gohash_db.ErrEntryNotFound && !isIDReference(args):
+			if yesNoQuestion("Note does not exist. Do you want to create it?", reader, true) {
+				changed = createNote(args, state, group, reader)
 			}
-		} else {
-			doCreate := createOnly ||
-				yesNoQuestion("Entry does not exist. Do you want to create it?", reader, true)
-			if doCreate {
-				newEntry := createOrEditEntry(entry, group, currentGroup, reader, nil)
-				(*state)[group] = append(entries, newEntry)
+		case err != nil:
+			fmt.Printf("Error: %s.\n", err.Error())
+		default:
+			showNote(&(*state)[entryGroup][entryIndex])
+		}
+	default:
+		entries := (*state)[group]
+		fmt.Printf("Showing notes in group %s:\n\n", group)
+		for _, e := range entries {
+			if e.Kind == gohash_db.NoteKind {
+				println(e.String())
 			}
 		}
-	} else {
-		println("Error: please provide the name of the entry to be created.")
+		println("\nHint: To show the contents of a single note, type 'note <name>'.")
 	}
+	return
 }
 
-func renameEntry(entry string, state *State, group string, reader *bufio.Reader) {
-	if len(entry) > 0 {
-		entries, _ := (*state)[group]
-
-		entryIndex, found := findEntryIndex(&entries, entry)
-		if !found && strings.Contains(entry, ":") {
-			// split up group:entry from user input
-			parts := strings.SplitN(entry, ":", 2)
-			group = parts[0]
-			entry = parts[1]
-			entries = (*state)[group]
-			entryIndex, found = findEntryIndex(&entries, entry)
+func (cmd attachCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	var option, attachment, entry string
+	switch {
+	case strings.HasPrefix(args, "-a"):
+		option, entry = "-a", strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-x"), strings.HasPrefix(args, "-d"):
+		option = args[:2]
+		parts := splitTrimN(strings.TrimSpace(args[2:]), 2)
+		attachment, entry = parts[0], parts[1]
+		if len(entry) == 0 {
+			println("Error: please provide the name of the attachment and of the entry.")
+			println("Hint: to extract the attachment 'id_rsa' of the entry 'server', type 'attach -x id_rsa server'.")
+			return
 		}
+	case strings.HasPrefix(args, "-"):
+		println("Error: unknown option. Type 'help attach' for usage.")
+		return
+	default:
+		entry = args
+	}
 
-		if found {
-			for {
-				newName := read(reader, "Please enter the new entry name: ")
-				if len(newName) == 0 {
-					println("Error: no name provided.")
-				} else if _, taken := findEntryIndex(&entries, newName); taken {
-					println("Error: name alredy taken.")
-				} else {
-					entries[entryIndex].Name = newName
-					break
-				}
-			}
+	if len(entry) == 0 {
+		println("Error: please provide the name of the entry.")
+		return
+	}
+	entryGroup, entryIndex, err := findEntry(state, group, entry)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	info := &(*state)[entryGroup][entryIndex]
+
+	switch option {
+	case "-a":
+		changed = addAttachment(info, state, reader)
+	case "-x":
+		extractAttachment(info, attachment, reader)
+	case "-d":
+		if info.RemoveAttachment(attachment) {
+			info.UpdatedAt = time.Now()
+			changed = true
 		} else {
-			println("Error: entry does not exist.")
+			fmt.Printf("Error: entry '%s' does not have an attachment called '%s'.\n", info.Name, attachment)
 		}
-	} else {
-		println("Error: please provide the name of the entry to be renamed.")
-	}
-}
-
-func editEntry(entry string, state *State, group string, reader *bufio.Reader) {
-	if len(entry) > 0 {
-		currentGroup := group
-		entries, _ := (*state)[group]
-
-		entryIndex, found := findEntryIndex(&entries, entry)
-		if !found && strings.Contains(entry, ":") {
-			// split up group:entry from user input
-			parts := strings.SplitN(entry, ":", 2)
-			group = parts[0]
-			entry = parts[1]
-			entries = (*state)[group]
-			entryIndex, found = findEntryIndex(&entries, entry)
+	default:
+		if len(info.Attachments) == 0 {
+			fmt.Printf("Entry '%s' has no attachments.\n", info.Name)
+			println("Hint: To attach a file to the entry, type 'attach -a <entry>'.")
+			return
 		}
-
-		if found {
-			fmt.Printf("Editing entry:\n%s\n", entries[entryIndex].String())
-			println("\nHint: to keep the current value for a field, don't enter a new value.\n")
-			entries[entryIndex] = createOrEditEntry(entry, currentGroup, group, reader, &entries[entryIndex])
-		} else {
-			println("Error: entry does not exist.")
+		fmt.Printf("Attachments of entry '%s':\n\n", info.Name)
+		for _, a := range info.Attachments {
+			fmt.Printf("  %-24s %12s  added at %s\n", a.Name, gohash_db.FormatSize(len(a.Data)),
+				a.AddedAt.Format("2006-01-02 15:04:05"))
 		}
-	} else {
-		println("Error: please provide the name of the entry to be edited.")
 	}
+	return
 }
 
-func removeEntry(entryName string, state *State, group string, reader *bufio.Reader) {
-	if len(entryName) == 0 {
-		println("Error: please provide the name of the entry to remove.")
-	} else {
-		removed := removeEntryFrom(state, group, entryName)
-		if !removed {
-			println("Error: entry does not exist. Are you within the correct group?")
-			println("Hint: To enter a group called <group-name>, type 'group group-name'.")
+func (cmd otpCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	option, entry := "", args
+	if strings.HasPrefix(args, "-") {
+		switch option = strings.SplitN(args, " ", 2)[0]; option {
+		case "-s", "-d", "-u":
+			entry = strings.TrimSpace(args[2:])
+		default:
+			println("Error: unknown option. Type 'help otp' for usage.")
+			return
 		}
 	}
-}
+	if len(entry) == 0 {
+		println("Error: please provide the name of the entry.")
+		return
+	}
+	entryGroup, entryIndex, err := findEntry(state, group, entry)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	info := &(*state)[entryGroup][entryIndex]
 
-func createOrEditEntry(name, group, currentGroup string, reader *bufio.Reader,
+	if option == "-s" {
+		return setUpOTP(info, reader)
+	}
+	if info.OTP == nil {
+		fmt.Printf("Error: one-time passwords are not set up for entry '%s'.\n", info.Name)
+		fmt.Printf("Hint: To set them up, type 'otp -s %s'.\n", entry)
+		return
+	}
+	switch option {
+	case "-d":
+		info.OTP = nil
+		info.UpdatedAt = time.Now()
+		changed = true
+	case "-u":
+		println(info.OTP.URI())
+	default:
+		if info.OTP.Type == gohash_db.HOTP && cmd.readOnly {
+			println("Error: counter-based one-time passwords cannot be generated in read-only mode, " +
+				"as the counter could not be saved.")
+			return
+		}
+		now := time.Now()
+		code, remaining, err := info.OTP.Code(now)
+		if err != nil {
+			fmt.Printf("Error: %s.\n", err.Error())
+			return
+		}
+		if info.OTP.Type == gohash_db.HOTP {
+			fmt.Printf("One-time password: %s\n", code)
+			// the counter was incremented, and merges keep the most recently updated version of an entry
+			info.UpdatedAt = now
+			changed = true
+		} else {
+			fmt.Printf("One-time password: %s (valid for %d more seconds)\n", code, int(remaining.Seconds()))
+		}
+		if err = clipboard.WriteAll(code); err != nil {
+			fmt.Printf("Error: unable to copy! Reason: %s\n", err.Error())
+		} else {
+			go removeFromClipboardAfterDelay(code)
+		}
+	}
+	return
+}
+
+func (cmd tagCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	var option, tags, entry string
+	switch {
+	case strings.HasPrefix(args, "-a"), strings.HasPrefix(args, "-d"):
+		option = args[:2]
+		parts := splitTrimN(strings.TrimSpace(args[2:]), 2)
+		tags, entry = parts[0], parts[1]
+		if len(entry) == 0 {
+			println("Error: please provide the tags and the name of the entry.")
+			println("Hint: to tag the entry 'aws' with 'work' and 'infra', type 'tag -a work,infra aws'.")
+			return
+		}
+	case strings.HasPrefix(args, "-"):
+		println("Error: unknown option. Type 'help tag' for usage.")
+		return
+	case len(args) == 0:
+		allTags, counts := state.Tags()
+		if len(allTags) == 0 {
+			println("There are no tags yet.")
+			println("Hint: To tag an entry, type 'tag -a <tag> <entry>'.")
+			return
+		}
+		println("Tags:\n")
+		for _, tag := range allTags {
+			if counts[tag] == 1 {
+				fmt.Printf("  %-24s 1 entry\n", tag)
+			} else {
+				fmt.Printf("  %-24s %d entries\n", tag, counts[tag])
+			}
+		}
+		println("\nHint: To list the entries with a tag, type 'entry --tag <tag>'.")
+		return
+	default:
+		entry = args
+	}
+
+	entryGroup, entryIndex, err := findEntry(state, group, entry)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	info := &(*state)[entryGroup][entryIndex]
+
+	for _, tag := range strings.Split(tags, ",") {
+		switch option {
+		case "-a":
+			added, err := info.AddTag(tag)
+			if err != nil {
+				fmt.Printf("Error: invalid tag '%s': %s.\n", tag, err.Error())
+			}
+			changed = changed || added
+		case "-d":
+			if info.RemoveTag(tag) {
+				changed = true
+			} else {
+				fmt.Printf("Error: entry '%s' does not have the tag '%s'.\n", info.Name, tag)
+			}
+		}
+	}
+	if changed {
+		info.UpdatedAt = time.Now()
+	}
+	if len(info.Tags) == 0 {
+		fmt.Printf("Entry '%s' has no tags.\n", info.Name)
+	} else {
+		fmt.Printf("Tags of entry '%s': %s\n", info.Name, strings.Join(info.Tags, ", "))
+	}
+	return
+}
+
+func (cmd findCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	if len(args) == 0 {
+		println("Error: please provide something to find.")
+		return
+	}
+	results := state.Search(args)
+	if len(results) == 0 {
+		fmt.Printf("No entries match '%s'.\n", args)
+		return
+	}
+	if len(results) > maxFindResults {
+		fmt.Printf("Showing the best %d of %d results:\n\n", maxFindResults, len(results))
+		results = results[:maxFindResults]
+	}
+	for i, result := range results {
+		info := &(*state)[result.Group][result.Index]
+		fmt.Printf("  %2d  %s:%s\n", i+1, result.Group, findResultDescription(info))
+	}
+	println("")
+
+	answer := read(reader, "Enter the number of an entry to use it (or just hit Enter to skip): ")
+	if len(answer) == 0 {
+		return
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(results) {
+		println("Error: invalid entry number.")
+		return
+	}
+	result := results[n-1]
+	info := &(*state)[result.Group][result.Index]
+	ref := "#" + info.ID
+
+	action := strings.ToLower(read(reader, "Do you want to (s)how the entry, copy its (p)assword or "+
+		"(u)sername, or (g)o to its URL? [s/p/u/g] (s): "))
+	switch action {
+	case "", "s":
+		showEntry(info)
+	case "p":
+		cpCommand{}.run(state, group, "-p "+ref, reader)
+	case "u":
+		cpCommand{}.run(state, group, "-u "+ref, reader)
+	case "g":
+		gotoCommand{}.run(state, group, ref, reader)
+	default:
+		println("Error: invalid option.")
+	}
+	return
+}
+
+func (cmd cmpCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	if len(args) > 0 {
+		println("Error: the cmp command does not accept any arguments.")
+	} else {
+		encryption.Zero(confirmMasterPassword(cmd.keyBox))
+		pass := createPassword()
+		defer encryption.Zero(pass)
+		changed = replaceSessionKey(cmd.keyBox, pass, cmd.keyBox.key.KDF())
+	}
+	return
+}
+
+func (cmd kdfCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	current := cmd.keyBox.key.KDF()
+	if len(args) == 0 {
+		showKDFParams(&current)
+		println("\nHint: To change the parameters, type 'kdf' followed by options. Type 'help kdf' for usage.")
+		return
+	}
+
+	params, err := parseKDFParams(args, current)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		println("Hint: Type 'help kdf' for usage.")
+		return
+	}
+
+	println("New parameters:\n")
+	showKDFParams(&params)
+	println("")
+	pass := confirmMasterPassword(cmd.keyBox)
+	defer encryption.Zero(pass)
+	if replaceSessionKey(cmd.keyBox, pass, params) {
+		println("Re-encrypting the database with the new parameters.")
+		return true
+	}
+	return false
+}
+
+func (cmd calibrateCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	flags := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	target := flags.Duration("t", time.Second, "")
+	maxMemory := flags.Uint("m", 256, "")
+
+	err := flags.Parse(strings.Fields(args))
+	if err == nil && flags.NArg() > 0 {
+		err = fmt.Errorf("unexpected argument: %s", flags.Arg(0))
+	}
+	if err == nil && (*target <= 0 || *maxMemory == 0 || *maxMemory > uint(encryption.MaxMemory/1024)) {
+		err = fmt.Errorf("parameter out of range")
+	}
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		println("Hint: Type 'help calibrate' for usage.")
+		return
+	}
+
+	fmt.Printf("Calibrating for an unlock time of %s, using at most %d MiB of memory...\n", *target, *maxMemory)
+	params := encryption.CalibrateArgon2(*target, uint32(*maxMemory)*1024)
+	current := cmd.keyBox.key.KDF()
+	params.SaltLen = current.SaltLen
+
+	println("\nProposed parameters:\n")
+	showKDFParams(&params)
+	println("\nCurrent parameters:\n")
+	showKDFParams(&current)
+	println("")
+
+	if yesNoQuestion("Do you want to apply the proposed parameters to the database?", reader, false) {
+		pass := confirmMasterPassword(cmd.keyBox)
+		defer encryption.Zero(pass)
+		if replaceSessionKey(cmd.keyBox, pass, params) {
+			println("Re-encrypting the database with the new parameters.")
+			changed = true
+		}
+	}
+	return
+}
+
+func (cmd backupCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	var (
+		VerifyBackup  bool
+		RestoreBackup bool
+		number        string
+	)
+	switch {
+	case strings.HasPrefix(args, "-v"):
+		VerifyBackup = true
+		number = strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-r"):
+		RestoreBackup = true
+		number = strings.TrimSpace(args[2:])
+	case len(args) > 0:
+		println("Error: unknown option. Type 'help backup' for usage.")
+		return
+	}
+
+	backups, err := gohash_db.ListBackups(cmd.dbPath)
+	if err != nil {
+		fmt.Printf("Error: unable to list backups! Reason: %s\n", err.Error())
+		return
+	}
+
+	if len(number) > 0 {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			println("Error: not a number. Please provide the number of a backup.")
+			return
+		}
+		var selected []gohash_db.Backup
+		for _, b := range backups {
+			if b.Number == n {
+				selected = append(selected, b)
+			}
+		}
+		if len(selected) == 0 {
+			fmt.Printf("Error: backup %d does not exist.\n", n)
+			return
+		}
+		backups = selected
+	} else if RestoreBackup {
+		println("Error: please provide the number of the backup to restore.")
+		return
+	}
+
+	switch {
+	case VerifyBackup:
+		var backupPass []byte
+		defer func() { encryption.Zero(backupPass) }()
+		for _, b := range backups {
+			backupState, err := readBackup(b, cmd.keyBox, &backupPass)
+			if err != nil {
+				fmt.Printf("  ✗ %d  %s\n", b.Number, err.Error())
+			} else {
+				fmt.Printf("  ✔ %d  %s\n", b.Number, stateDescription(&backupState))
+			}
+		}
+	case RestoreBackup:
+		changed = restoreBackup(backups[0], state, cmd.keyBox, reader)
+	default:
+		switch len(backups) {
+		case 0:
+			println("There are no backups yet.")
+			return
+		case 1:
+			println("There is 1 backup:\n")
+		default:
+			fmt.Printf("There are %d backups:\n\n", len(backups))
+		}
+		for _, b := range backups {
+			fmt.Printf("  %-4d %s  (%d bytes)\n", b.Number, b.ModTime.Format("2006-01-02 15:04:05"), b.Size)
+		}
+		println("\nHint: To restore a backup, type 'backup -r <number>'.")
+	}
+	return
+}
+
+func (cmd undoCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	if cmd.redo {
+		change := cmd.history.NextRedo()
+		if change == nil {
+			println("There is nothing to redo.")
+			return
+		}
+		fmt.Printf("Redoing '%s' %s.\n", change.Description, change.RedoSummary())
+		if yesNoQuestion("Do you want to continue?", reader, true) {
+			cmd.history.Redo(state)
+			cmd.exitRemovedGroup(state)
+			changed = true
+		}
+		return
+	}
+	change := cmd.history.NextUndo()
+	if change == nil {
+		println("There is nothing to undo.")
+		return
+	}
+	fmt.Printf("Undoing '%s' %s.\n", change.Description, change.UndoSummary())
+	if yesNoQuestion("Do you want to continue?", reader, true) {
+		cmd.history.Undo(state)
+		cmd.exitRemovedGroup(state)
+		changed = true
+	}
+	return
+}
+
+// exitRemovedGroup goes up to the closest existing group if the current group no longer exists.
+func (cmd undoCommand) exitRemovedGroup(state *State) {
+	for cmd.groupBox.value != gohash_db.DefaultGroup && !state.GroupExists(cmd.groupBox.value) {
+		cmd.groupBox.value = gohash_db.ParentGroup(cmd.groupBox.value)
+	}
+}
+
+func (cmd trashCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	var (
+		RestoreItem bool
+		PurgeItem   bool
+		number      string
+	)
+	switch {
+	case strings.HasPrefix(args, "-r"):
+		RestoreItem = true
+		number = strings.TrimSpace(args[2:])
+	case strings.HasPrefix(args, "-p"):
+		PurgeItem = true
+		number = strings.TrimSpace(args[2:])
+	case args == "-e":
+		return emptyTrash(state, reader)
+	case len(args) > 0:
+		println("Error: unknown option. Type 'help trash' for usage.")
+		return
+	}
+
+	items := state.Trash()
+	if !RestoreItem && !PurgeItem {
+		listTrash(items, cmd.retention)
+		return
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		println("Error: please provide the number of an item in the trash. Type 'trash' to list them.")
+		return
+	}
+	if n < 1 || n > len(items) {
+		fmt.Printf("Error: item %d does not exist.\n", n)
+		return
+	}
+	item := items[n-1]
+	if RestoreItem {
+		for _, ref := range state.RestoreTrashItem(item) {
+			fmt.Printf("Restored %s:%s\n", ref.Group, (*state)[ref.Group][ref.Index].Name)
+		}
+		if item.IsGroup() {
+			fmt.Printf("Group '%s' restored.\n", item.Group)
+		}
+		return true
+	}
+	if yesNoQuestion(fmt.Sprintf("Are you sure you want to permanently delete %s?", trashItemDescription(&item)),
+		reader, false) {
+		state.PurgeTrashItem(item)
+		return true
+	}
+	println("Aborted!")
+	return
+}
+
+func (cmd saveCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	switch {
+	case args == "":
+		if cmd.save() {
+			println("Database saved.")
+		}
+	case strings.HasPrefix(args, "-a"):
+		switch strings.TrimSpace(args[2:]) {
+		case "on":
+			*cmd.autosave = true
+			println("Autosave is on.")
+		case "off":
+			*cmd.autosave = false
+			println("Autosave is off. Type 'save' to save changes.")
+		default:
+			println("Error: please use 'on' or 'off' with the -a option.")
+		}
+	default:
+		println("Error: unknown option. Type 'help save' for usage.")
+	}
+	return false
+}
+
+func (cmd backupCommand) backupNumbers() []string {
+	backups, _ := gohash_db.ListBackups(cmd.dbPath)
+	result := make([]string, len(backups), len(backups))
+	for i, b := range backups {
+		result[i] = strconv.Itoa(b.Number)
+	}
+	return result
+}
+
+func (cmd importCommand) run(state *State, group, args string, reader *bufio.Reader) (changed bool) {
+	options, args, ok := parseImportOptions(args)
+	if !ok {
+		return
+	}
+	parts := strings.SplitN(args, " ", 2)
+	read, ok := importReaders[parts[0]]
+	if !ok {
+		if len(parts[0]) == 0 {
+			println("Error: please provide the source of the entries to import.")
+		} else {
+			fmt.Printf("Error: unknown source '%s'.\n", parts[0])
+		}
+		fmt.Printf("Hint: The supported sources are: %s.\n", strings.Join(importSources(), ", "))
+		return
+	}
+	if len(parts) < 2 || len(strings.TrimSpace(parts[1])) == 0 {
+		println("Error: please provide the path of the file to import.")
+		return
+	}
+	path, err := homedir.Expand(strings.TrimSpace(parts[1]))
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	export, err := readImportFile(path, read)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	if options.group != "" {
+		export.MoveTo(options.group)
+	}
+	if options.dryRun {
+		// import into a copy of the state, so that the report shows exactly what would be done
+		dryRunState := state.Copy()
+		report := importer.Import(dryRunState, export, time.Now(), options.policy)
+		printImportDryRun(&dryRunState, &report)
+		return
+	}
+	report := importer.Import(*state, export, time.Now(), options.policy)
+	printImportReport(&report)
+	changed = len(report.Imported) > 0 || len(report.Groups) > 0
+	if changed {
+		*cmd.afterSave = func() { offerToShredExportFile(path, reader, cmd.save) }
+	}
+	return
+}
+
+// ============= Trash helper functions ============= //
+
+func listTrash(items []gohash_db.TrashItem, retention time.Duration) {
+	switch len(items) {
+	case 0:
+		println("The trash is empty.")
+		return
+	case 1:
+		println("There is 1 item in the trash:\n")
+	default:
+		fmt.Printf("There are %d items in the trash:\n\n", len(items))
+	}
+	for i := range items {
+		item := &items[i]
+		fmt.Printf("  %-4d %s  %s\n", i+1, item.DeletedAt.Format("2006-01-02 15:04:05"), trashItemDescription(item))
+	}
+	if retention > 0 {
+		fmt.Printf("\nItems are permanently deleted %s after being moved to the trash.\n", retentionDescription(retention))
+	}
+	println("\nHint: To restore an item, type 'trash -r <number>'.")
+}
+
+func trashItemDescription(item *gohash_db.TrashItem) string {
+	if !item.IsGroup() {
+		return fmt.Sprintf("entry '%s' of group '%s'", item.Entry, item.Group)
+	}
+	if item.Entries == 1 {
+		return fmt.Sprintf("group '%s' (1 entry)", item.Group)
+	}
+	return fmt.Sprintf("group '%s' (%d entries)", item.Group, item.Entries)
+}
+
+func retentionDescription(retention time.Duration) string {
+	days := int(retention / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func emptyTrash(state *State, reader *bufio.Reader) bool {
+	entriesLen := len((*state)[gohash_db.TrashGroup])
+	if entriesLen == 0 {
+		println("The trash is already empty.")
+		return false
+	}
+	if yesNoQuestion(fmt.Sprintf("Are you sure you want to permanently delete all (%d) entries in the trash?",
+		entriesLen), reader, false) {
+		state.EmptyTrash()
+		return true
+	}
+	println("Aborted!")
+	return false
+}
+
+// ============= Import helper functions ============= //
+
+// importReaders the functions reading the export files of each source the import command supports.
+var importReaders = map[string]func(r io.Reader) (*importer.Export, error){
+	"keepass":   importer.ReadKeePass,
+	"bitwarden": importer.ReadBitwarden,
+	"1password": importer.ReadOnePassword,
+	"browser":   importer.ReadBrowserCSV,
+}
+
+func importSources() []string {
+	sources := make([]string, 0, len(importReaders))
+	for source := range importReaders {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// parseImportOptions parses the options of the import command, which precede the source.
+func parseImportOptions(args string) (options importOptions, rest string, ok bool) {
+	rest = args
+	for {
+		switch {
+		case rest == "-n" || strings.HasPrefix(rest, "-n "):
+			options.dryRun = true
+			rest = strings.TrimSpace(rest[2:])
+		case rest == "-c" || strings.HasPrefix(rest, "-c "):
+			parts := splitTrimN(strings.TrimSpace(rest[2:]), 2)
+			var err error
+			if options.policy, err = importer.ParsePolicy(parts[0]); err != nil {
+				fmt.Printf("Error: %s.\n", err.Error())
+				return options, rest, false
+			}
+			rest = parts[1]
+		case rest == "-g" || strings.HasPrefix(rest, "-g "):
+			parts := splitTrimN(strings.TrimSpace(rest[2:]), 2)
+			var err error
+			if options.group, err = gohash_db.CleanGroupPath(parts[0]); err != nil {
+				fmt.Printf("Error: %s.\n", err.Error())
+				return options, rest, false
+			}
+			rest = parts[1]
+		default:
+			return options, rest, true
+		}
+	}
+}
+
+func readImportFile(path string, read func(r io.Reader) (*importer.Export, error)) (*importer.Export, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return read(file)
+}
+
+func printImportReport(report *importer.Report) {
+	switch len(report.Imported) {
+	case 0:
+		println("No entries were imported.")
+	case 1:
+		println("Imported 1 entry.")
+	default:
+		fmt.Printf("Imported %d entries.\n", len(report.Imported))
+	}
+	if len(report.Groups) > 0 {
+		fmt.Printf("Created groups: %s\n", strings.Join(report.Groups, ", "))
+	}
+	printImportProblems(report)
+}
+
+// offerToShredExportFile warns that an export file is not encrypted, offering to securely delete it once the
+// database is saved.
+func offerToShredExportFile(path string, reader *bufio.Reader, save func() bool) {
+	fmt.Printf("\nWarning: '%s' is not encrypted, anyone who can read it can see your passwords.\n", path)
+	if !yesNoQuestion("Do you want to securely overwrite and delete the file, once the database is saved?", reader, false) {
+		println("Hint: Remember to delete the file once you no longer need it.")
+		return
+	}
+	if !save() {
+		println("Error: the file was not deleted, as the database was not saved.")
+		return
+	}
+	if err := importer.Shred(path); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	fmt.Printf("Deleted '%s'.\n", path)
+}
+
+// printImportDryRun prints what an import would do, given the state it was done on and its report.
+func printImportDryRun(state *State, report *importer.Report) {
+	println("Dry run, no changes were made.")
+	switch len(report.Imported) {
+	case 0:
+		println("\nNo entries would be imported.")
+	case 1:
+		println("\nThe following entry would be imported:")
+	default:
+		fmt.Printf("\nThe following %d entries would be imported:\n", len(report.Imported))
+	}
+	for _, ref := range report.Imported {
+		fmt.Printf("  %s:%s\n", ref.Group, (*state)[ref.Group][ref.Index].Name)
+	}
+	if len(report.Groups) > 0 {
+		fmt.Printf("\nThe following groups would be created: %s\n", strings.Join(report.Groups, ", "))
+	}
+	printImportProblems(report)
+}
+
+// printImportProblems prints the duplicate entries and the skipped items of an import.
+func printImportProblems(report *importer.Report) {
+	if len(report.Duplicates) > 0 {
+		println("\nThe following entries have the same names as other entries in their groups:")
+		for _, duplicate := range report.Duplicates {
+			var resolution string
+			switch duplicate.Resolution {
+			case importer.RenameDuplicates:
+				resolution = fmt.Sprintf("renamed to '%s'", duplicate.NewName)
+			case importer.OverwriteDuplicates:
+				resolution = "overwrites the existing entry, which is moved to the trash"
+			default:
+				resolution = "skipped"
+			}
+			fmt.Printf("  %s:%s (%s)\n", duplicate.Group, duplicate.Info.Name, resolution)
+		}
+		if report.Duplicates[0].Resolution == importer.SkipDuplicates {
+			println("Hint: Use the -c option to rename or overwrite them, see 'help import'.")
+		}
+	}
+	if len(report.Skipped) > 0 {
+		println("\nThe following items could not be imported:")
+		for _, skipped := range report.Skipped {
+			fmt.Printf("  %s: %s\n", skipped.Item, skipped.Reason)
+		}
+	}
+}
+
+// ============= Backup helper functions ============= //
+
+// readBackup reads a backup using the current session key or, if the backup was saved with a different
+// master password or KDF parameters, with the backup's master password, which is asked for if not known yet.
+func readBackup(backup gohash_db.Backup, keyBox *sessionKeyBox, backupPass *[]byte) (State, error) {
+	backupState, err := gohash_db.ReadDatabaseWithKey(backup.Path, keyBox.key)
+	if err != gohash_db.ErrKeyMismatch {
+		return backupState, err
+	}
+	if *backupPass == nil {
+		fmt.Printf("Backup %d was saved with a different master password or KDF parameters.\n", backup.Number)
+		print("Please enter the master password of the backup: ")
+		pass, err := terminal.ReadPassword(int(syscall.Stdin))
+		println("")
+		if err != nil {
+			panic(err)
+		}
+		*backupPass = pass
+	}
+	return readDatabaseWithPassword(backup.Path, *backupPass)
+}
+
+func restoreBackup(backup gohash_db.Backup, state *State, keyBox *sessionKeyBox, reader *bufio.Reader) bool {
+	var backupPass []byte
+	defer func() { encryption.Zero(backupPass) }()
+	backupState, err := readBackup(backup, keyBox, &backupPass)
+	for attempts := 0; err != nil && backupPass != nil && attempts < 3; attempts++ {
+		fmt.Printf("Error: %s\n", err.Error())
+		print("Please enter the master password of the backup (or just hit Enter to abort): ")
+		pass, err2 := terminal.ReadPassword(int(syscall.Stdin))
+		println("")
+		if err2 != nil {
+			panic(err2)
+		}
+		encryption.Zero(backupPass)
+		backupPass = pass
+		if len(pass) == 0 {
+			println("Aborted!")
+			return false
+		}
+		backupState, err = readDatabaseWithPassword(backup.Path, pass)
+	}
+	if err != nil {
+		fmt.Printf("Error: unable to open backup (%s).\n", err.Error())
+		return false
+	}
+
+	question := fmt.Sprintf("Backup %d contains %s. Do you want to replace the current %s with it?",
+		backup.Number, stateDescription(&backupState), stateDescription(state))
+	if yesNoQuestion(question, reader, false) {
+		if _, ok := backupState[gohash_db.DefaultGroup]; !ok {
+			backupState[gohash_db.DefaultGroup] = []LoginInfo{}
+		}
+		*state = backupState
+		println("Backup restored! The previous state of the database is kept as the most recent backup.")
+		return true
+	}
+	println("Aborted!")
+	return false
+}
+
+func stateDescription(state *State) string {
+	groupCount, entryCount := 0, 0
+	for group, entries := range *state {
+		if group != gohash_db.TrashGroup {
+			groupCount++
+			entryCount += len(entries)
+		}
+	}
+	return fmt.Sprintf("%d groups and %d entries", groupCount, entryCount)
+}
+
+// ============= Entry helper functions ============= //
+
+func createOrShowEntry(entry string, state *State, group string,
+	reader *bufio.Reader, createOnly bool) (changed bool) {
+	currentGroup := group
+	if isIDReference(entry) {
+		entryGroup, entryIndex, err := findEntry(state, group, entry)
+		switch {
+		case err != nil:
+			fmt.Printf("Error: %s.\n", err.Error())
+		case createOnly:
+			println("Error: entry already exists.")
+		default:
+			showEntry(&(*state)[entryGroup][entryIndex])
+		}
+		return
+	}
+	if len(entry) > 0 {
+		entries, _ := (*state)[group]
+		if strings.Contains(entry, ":") {
+			// split up group:entry from user input
+			parts := strings.SplitN(entry, ":", 2)
+			candidateGroup := parts[0]
+			candidateEntry := parts[1]
+			useCandidates := ask2OptionsQuestion(
+				"Option 1: create or view entry '"+candidateEntry+"' in group '"+candidateGroup+"'.\n"+
+					"Option 2: create or view entry '"+entry+"' in group '"+group+"'.\n\n"+
+					"Which option do you prefer?", reader, "1", "2", true)
+			if useCandidates {
+				var err error
+				if group, err = gohash_db.CleanGroupPath(candidateGroup); err != nil {
+					fmt.Printf("Error: %s.\n", err.Error())
+					return
+				}
+				entry = candidateEntry
+				entries = (*state)[group]
+				if !state.GroupExists(group) {
+					newGroupWanted := yesNoQuestion("Group does not exist, do you want to create it?", reader, true)
+					if newGroupWanted {
+						_, changed = createGroup(group, state, group)
+					} else {
+						return
+					}
+				}
+			}
+		}
+
+		entryIndex, exists := findEntryIndex(&entries, entry)
+		if exists {
+			if createOnly {
+				println("Error: entry already exists.")
+			} else {
+				showEntry(&entries[entryIndex])
+			}
+		} else {
+			doCreate := createOnly ||
+				yesNoQuestion("Entry does not exist. Do you want to create it?", reader, true)
+			if doCreate {
+				newEntry := createOrEditEntry(entry, group, currentGroup, reader, nil)
+				(*state)[group] = append(entries, newEntry)
+				changed = true
+			}
+		}
+	} else {
+		println("Error: please provide the name of the entry to be created.")
+	}
+	return
+}
+
+func listEntriesWithTag(tag string, state *State, output outputOptions) {
+	if len(tag) == 0 {
+		println("Error: please provide a tag.")
+		println("Hint: To list all tags, type 'tag'.")
+		return
+	}
+	refs := state.FindByTag(tag)
+	if output.format != gohash_db.TableFormat {
+		listing := gohash_db.Listing{SchemaVersion: gohash_db.OutputSchemaVersion, Tag: tag,
+			Groups: []gohash_db.GroupOutput{}, Entries: []gohash_db.EntryOutput{}}
+		for _, ref := range refs {
+			info := &(*state)[ref.Group][ref.Index]
+			listing.Entries = append(listing.Entries, info.Output(ref.Group, output.withSecrets))
+		}
+		writeDocument(output.format, listing)
+		return
+	}
+	if len(refs) == 0 {
+		fmt.Printf("There are no entries with the tag '%s'.\n", tag)
+		return
+	}
+	fmt.Printf("Showing entries with the tag '%s':\n\n", tag)
+	for _, ref := range refs {
+		fmt.Printf("[%s]\n%s\n", ref.Group, (*state)[ref.Group][ref.Index].String())
+	}
+}
+
+// maxFindResults the maximum number of results shown by the find command.
+const maxFindResults = 20
+
+// findResultDescription describes an entry found by the find command in a single line.
+func findResultDescription(info *LoginInfo) string {
+	details := make([]string, 0, 3)
+	for _, detail := range []string{info.Username, info.URL} {
+		if len(detail) > 0 {
+			details = append(details, detail)
+		}
+	}
+	if len(info.Tags) > 0 {
+		details = append(details, "tags: "+strings.Join(info.Tags, ", "))
+	}
+	if len(details) == 0 {
+		return info.Name
+	}
+	return info.Name + " (" + strings.Join(details, ", ") + ")"
+}
+
+func showEntry(info *LoginInfo) {
+	println(info.String())
+	if info.Kind == gohash_db.NoteKind {
+		fmt.Printf("\nHint: To show the contents of the note, type 'note %s'.\n", info.Name)
+	}
+}
+
+func renameEntry(entry string, state *State, group string, reader *bufio.Reader) bool {
+	if len(entry) > 0 {
+		entryGroup, entryIndex, err := findEntry(state, group, entry)
+
+		if err == nil {
+			entries := (*state)[entryGroup]
+			for {
+				newName := read(reader, "Please enter the new entry name: ")
+				if len(newName) == 0 {
+					println("Error: no name provided.")
+				} else if isIDReference(newName) {
+					println("Error: entry names cannot start with '#'.")
+				} else if _, taken := findEntryIndex(&entries, newName); taken {
+					println("Error: name alredy taken.")
+				} else {
+					entries[entryIndex].Name = newName
+					// merges keep the most recently updated version of an entry
+					entries[entryIndex].UpdatedAt = time.Now()
+					return true
+				}
+			}
+		} else {
+			fmt.Printf("Error: %s.\n", err.Error())
+		}
+	} else {
+		println("Error: please provide the name of the entry to be renamed.")
+	}
+	return false
+}
+
+func editEntry(entry string, state *State, group string, reader *bufio.Reader) bool {
+	if len(entry) > 0 {
+		entryGroup, entryIndex, err := findEntry(state, group, entry)
+
+		if err == nil {
+			entries := (*state)[entryGroup]
+			if entries[entryIndex].Kind == gohash_db.NoteKind {
+				return editNote(&entries[entryIndex], reader)
+			}
+			fmt.Printf("Editing entry:\n%s\n", entries[entryIndex].String())
+			println("\nHint: to keep the current value for a field, don't enter a new value.\n")
+			entries[entryIndex] = createOrEditEntry(entries[entryIndex].Name, entryGroup, group, reader,
+				&entries[entryIndex])
+			return true
+		}
+		fmt.Printf("Error: %s.\n", err.Error())
+	} else {
+		println("Error: please provide the name of the entry to be edited.")
+	}
+	return false
+}
+
+// showPasswordHistory lists the previous passwords of an entry, then offers to copy or restore one of them.
+func showPasswordHistory(entry string, state *State, group string, reader *bufio.Reader) bool {
+	if len(entry) == 0 {
+		println("Error: please provide the name of the entry.")
+		return false
+	}
+	entryGroup, entryIndex, err := findEntry(state, group, entry)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return false
+	}
+	info := &(*state)[entryGroup][entryIndex]
+	history := info.PasswordHistory
+	if len(history) == 0 {
+		fmt.Printf("The password of entry '%s' has never been changed.\n", info.Name)
+		return false
+	}
+
+	fmt.Printf("Previous passwords of entry '%s' (most recent first):\n\n", info.Name)
+	for i, record := range history {
+		fmt.Printf("  %2d  ********  replaced at %s\n", i+1, record.ReplacedAt.Format("2006-01-02 15:04:05"))
+	}
+	println("")
+
+	answer := read(reader, "Enter the number of a password to copy or restore it (or just hit Enter to skip): ")
+	if len(answer) == 0 {
+		return false
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(history) {
+		println("Error: invalid password number.")
+		return false
+	}
+
+	if ask2OptionsQuestion("Do you want to copy the password to the clipboard, or restore it?", reader, "c", "r", true) {
+		content := history[n-1].Password
+		if err = clipboard.WriteAll(content); err != nil {
+			fmt.Printf("Error: unable to copy! Reason: %s\n", err.Error())
+		} else {
+			go removeFromClipboardAfterDelay(content)
+		}
+		return false
+	}
+	if err = info.RestorePassword(n-1, time.Now()); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return false
+	}
+	println("Password restored. The replaced password was added to the password history.")
+	return true
+}
+
+func removeEntry(entryName string, state *State, group string, reader *bufio.Reader) bool {
+	if len(entryName) == 0 {
+		println("Error: please provide the name of the entry to remove.")
+		return false
+	}
+	entryGroup, entryIndex, err := findEntry(state, group, entryName)
+	if err == gohash_db.ErrEntryNotFound {
+		println("Error: entry does not exist. Are you within the correct group?")
+		println("Hint: To enter a group called <group-name>, type 'group group-name'.")
+		return false
+	} else if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return false
+	}
+	state.DeleteEntry(gohash_db.EntryRef{Group: entryGroup, Index: entryIndex}, time.Now())
+	println("Entry moved to the trash. Type 'trash' to restore it.")
+	return true
+}
+
+// moveOrCopyEntry moves or duplicates an entry. args contains a reference to the entry, optionally followed
+// by the target group, which is required to move the entry.
+func moveOrCopyEntry(args string, state *State, group string, reader *bufio.Reader, move bool) bool {
+	if len(args) == 0 {
+		println("Error: please provide the name of the entry.")
+		return false
+	}
+	// the target group is the last word, unless the whole argument refers to an entry to duplicate
+	ref, target := args, ""
+	entryGroup, entryIndex, err := findEntry(state, group, ref)
+	if err != nil || move {
+		if i := strings.LastIndex(args, " "); i > 0 {
+			ref, target = strings.TrimSpace(args[:i]), args[i+1:]
+			entryGroup, entryIndex, err = findEntry(state, group, ref)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return false
+	}
+	if move && len(target) == 0 {
+		println("Error: please provide the group to move the entry to.")
+		println("Hint: to move the entry 'gmail' to the 'personal' group, type 'entry -m gmail personal'.")
+		return false
+	}
+
+	targetGroup := entryGroup
+	changed := false
+	if len(target) > 0 {
+		var exists, ok bool
+		if targetGroup, exists, ok = resolveGroup(target, state, group); !ok {
+			return false
+		}
+		if !exists {
+			if !yesNoQuestion("Group '"+targetGroup+"' does not exist, do you want to create it?", reader, true) {
+				return false
+			}
+			_, changed = createGroup(targetGroup, state, group)
+		}
+	}
+
+	source := gohash_db.EntryRef{Group: entryGroup, Index: entryIndex}
+	name := (*state)[entryGroup][entryIndex].Name
+	if i, exists := state.FindEntry(targetGroup, name); exists && targetGroup == entryGroup && i == entryIndex {
+		// the entry is moved to its own group, which does nothing, or duplicated within its group
+		if !move {
+			name = state.UniqueEntryName(targetGroup, name)
+		}
+	} else if exists {
+		var overwrite, ok bool
+		if name, overwrite, ok = resolveEntryCollision(name, targetGroup, state, reader); !ok {
+			return changed
+		}
+		if overwrite {
+			// the overwritten entry is moved to the trash, so that it can be restored
+			state.DeleteEntry(gohash_db.EntryRef{Group: targetGroup, Index: i}, time.Now())
+			if targetGroup == entryGroup && i < entryIndex {
+				source.Index--
+			}
+		}
+	}
+
+	if move {
+		_, err = state.MoveEntry(source, targetGroup, name)
+	} else {
+		_, err = state.CopyEntry(source, targetGroup, name, time.Now())
+	}
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return changed
+	}
+	if move {
+		fmt.Printf("Moved entry to %s:%s\n", targetGroup, name)
+	} else {
+		fmt.Printf("Created entry %s:%s\n", targetGroup, name)
+	}
+	return true
+}
+
+// resolveEntryCollision asks the user what to do when an entry is moved or copied to a group that already has
+// an entry with the same name, returning the name the entry should have, whether the existing entry should be
+// overwritten, and whether to go ahead.
+func resolveEntryCollision(name, group string, state *State, reader *bufio.Reader) (string, bool, bool) {
+	fmt.Printf("An entry called '%s' already exists in group '%s'.\n", name, group)
+	for {
+		answer := strings.ToLower(read(reader,
+			"Do you want to (r)ename the entry, (o)verwrite the existing entry or (c)ancel? [r/o/c] (r): "))
+		switch answer {
+		case "", "r":
+			suggestion := state.UniqueEntryName(group, name)
+			for {
+				newName := read(reader, fmt.Sprintf("Enter the new entry name (%s): ", suggestion))
+				if len(newName) == 0 {
+					newName = suggestion
+				}
+				if isIDReference(newName) {
+					println("Error: entry names cannot start with '#'.")
+				} else if _, taken := state.FindEntry(group, newName); taken {
+					println("Error: name already taken.")
+				} else {
+					return newName, false, true
+				}
+			}
+		case "o":
+			return name, true, true
+		case "c":
+			println("Aborted!")
+			return name, false, false
+		default:
+			println("Please answer 'r', 'o' or 'c'.")
+		}
+	}
+}
+
+func createOrEditEntry(name, group, currentGroup string, reader *bufio.Reader,
 	entry *LoginInfo) (result LoginInfo) {
 	username := read(reader, "Enter username: ")
 
@@ -848,31 +2839,105 @@ func createOrEditEntry(name, group, currentGroup string, reader *bufio.Reader,
 		}
 	}
 
+	result = setEntryValues(name, entry, entryValues{username: username, URL: URL, description: description,
+		password: password}, time.Now())
+
+	fieldsQuestion := "Do you want to add custom fields?"
+	if len(result.Fields) > 0 {
+		fieldsQuestion = "Do you want to edit the custom fields?"
+	}
+	if yesNoQuestion(fieldsQuestion, reader, false) {
+		editCustomFields(&result, reader)
+	}
+
+	return
+}
+
+// entryValues the values given to an entry when it's created or edited.
+type entryValues struct {
+	username    string
+	URL         string
+	description string
+	password    string
+}
+
+// setEntryValues creates an entry with the given values or, if entry is not nil, returns a copy of the entry
+// with the given values, keeping the current value of any empty value.
+func setEntryValues(name string, entry *LoginInfo, values entryValues, now time.Time) (result LoginInfo) {
 	if entry != nil {
-		if username == "" {
-			username = entry.Username
+		// keep everything that is not given, e.g. attachments
+		result = entry.Copy()
+		if values.username == "" {
+			values.username = entry.Username
 		}
-		if URL == "" {
-			URL = entry.URL
+		if values.URL == "" {
+			values.URL = entry.URL
 		}
-		if password == "" {
-			password = entry.Password
+		if values.password == "" {
+			values.password = entry.Password
 		}
-		if description == "" {
-			description = entry.Description
+		if values.description == "" {
+			values.description = entry.Description
 		}
+	} else {
+		result.ID = gohash_db.NewEntryID()
+		result.CreatedAt = now
 	}
-
 	result.Name = name
-	result.Username = username
-	result.URL = URL
-	result.Password = password
-	result.Description = description
-	result.UpdatedAt = time.Now()
-
+	result.Username = values.username
+	result.URL = values.URL
+	result.SetPassword(values.password, now)
+	result.Description = values.description
+	result.UpdatedAt = now
 	return
 }
 
+// editCustomFields lets the user add, change and remove the custom fields of an entry.
+func editCustomFields(info *LoginInfo, reader *bufio.Reader) {
+	for {
+		if len(info.Fields) > 0 {
+			println("Custom fields:")
+			for i := range info.Fields {
+				fmt.Printf("  %-16s %s\n", info.Fields[i].Name+":", info.Fields[i].DisplayValue())
+			}
+		}
+		name := read(reader, "Enter the name of a field to add or change it, '-<name>' to remove it, "+
+			"or just hit Enter to finish: ")
+		switch {
+		case len(name) == 0:
+			return
+		case strings.HasPrefix(name, "-"):
+			if !info.RemoveField(strings.TrimSpace(name[1:])) {
+				println("Error: field does not exist.")
+			}
+		default:
+			var current CustomField
+			existing, exists := info.Field(name)
+			if exists {
+				current = *existing
+				println("Hint: to keep the current value, don't enter a new value.")
+			}
+			secret := yesNoQuestion("Is the field secret (its value will not be displayed)?", reader, current.Secret)
+			var value string
+			if secret {
+				print("Enter the value: ")
+				bytes, err := terminal.ReadPassword(int(syscall.Stdin))
+				println("")
+				if err != nil {
+					panic(err)
+				}
+				value = string(bytes)
+			} else {
+				value = read(reader, "Enter the value: ")
+			}
+			if len(value) == 0 {
+				value = current.Value
+			}
+			info.SetField(CustomField{Name: name, Value: value, Secret: secret})
+		}
+	}
+}
+
 func findEntryIndex(entries *[]LoginInfo, name string) (int, bool) {
 	for i, e := range *entries {
 		if name == e.Name {
@@ -882,118 +2947,488 @@ func findEntryIndex(entries *[]LoginInfo, name string) (int, bool) {
 	return -1, false
 }
 
-func removeEntryFrom(state *State, group, entry string) bool {
+// isIDReference checks whether ref refers to an entry by ID, i.e. #<ID prefix>.
+func isIDReference(ref string) bool {
+	return strings.HasPrefix(ref, "#")
+}
+
+// findEntry finds the entry referred to by ref, returning its group and its index within the group.
+//
+// ref may be the name of an entry in the current group, <group>:<name>, or #<ID prefix>, where the prefix
+// must match the ID of a single entry in any group.
+func findEntry(state *State, group, ref string) (string, int, error) {
 	entries := (*state)[group]
-	i, found := findEntryIndex(&entries, entry)
-	if !found && strings.Contains(entry, ":") {
+	if entryIndex, found := findEntryIndex(&entries, ref); found {
+		return group, entryIndex, nil
+	}
+	if isIDReference(ref) && len(ref) > 1 {
+		return state.FindByIDPrefix(ref[1:])
+	}
+	if strings.Contains(ref, ":") {
 		// split up group:entry from user input
-		parts := strings.SplitN(entry, ":", 2)
-		group = parts[0]
-		entry = parts[1]
-		entries = (*state)[group]
-		i, found = findEntryIndex(&entries, entry)
+		parts := strings.SplitN(ref, ":", 2)
+		entries = (*state)[parts[0]]
+		if entryIndex, found := findEntryIndex(&entries, parts[1]); found {
+			return parts[0], entryIndex, nil
+		}
+	}
+	return "", -1, gohash_db.ErrEntryNotFound
+}
+
+// entryField returns the value of a field of an entry, which may be the password, username, url, description,
+// note or id of the entry, or the name of one of its custom fields.
+func entryField(info *LoginInfo, name string) (string, bool) {
+	switch name {
+	case "password":
+		return info.Password, true
+	case "username":
+		return info.Username, true
+	case "url":
+		return info.URL, true
+	case "description":
+		return info.Description, true
+	case "note":
+		return strings.TrimSuffix(info.Note, "\n"), true
+	case "id":
+		return info.ID, true
 	}
-	if found {
-		(*state)[group] = append(entries[:i], entries[i+1:]...)
+	if field, ok := info.Field(name); ok {
+		return field.Value, true
 	}
-	return found
+	return "", false
 }
 
-// ============= Group helper functions ============= //
+// ============= Note helper functions ============= //
 
-func createGroup(name string, state *State, group string, reader *bufio.Reader) string {
-	if len(name) > 0 {
-		_, ok := (*state)[name]
-		if !ok {
-			(*state)[name] = []LoginInfo{}
-			return name
+// noteSentinel the line that terminates a note entered in the terminal.
+const noteSentinel = "."
+
+// findNote finds the note referred to by ref (see findEntry), printing an error if it can't be found.
+func findNote(state *State, group, ref string) (string, int, bool) {
+	if len(ref) == 0 {
+		println("Error: please provide the name of the note.")
+		return "", -1, false
+	}
+	entryGroup, entryIndex, err := findEntry(state, group, ref)
+	if err == gohash_db.ErrEntryNotFound {
+		fmt.Printf("Error: note '%s' does not exist.\n", ref)
+		return "", -1, false
+	} else if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return "", -1, false
+	}
+	if (*state)[entryGroup][entryIndex].Kind != gohash_db.NoteKind {
+		fmt.Printf("Error: entry '%s' is not a note.\n", ref)
+		return "", -1, false
+	}
+	return entryGroup, entryIndex, true
+}
+
+func showNote(info *LoginInfo) {
+	if info.Kind != gohash_db.NoteKind {
+		fmt.Printf("Error: entry '%s' is not a note.\n", info.Name)
+		return
+	}
+	fmt.Printf("%s:\n\n%s\n", info.Name, strings.TrimSuffix(info.Note, "\n"))
+}
+
+func createNote(name string, state *State, group string, reader *bufio.Reader) bool {
+	entries := (*state)[group]
+	switch {
+	case len(name) == 0:
+		println("Error: please provide the name of the note to be created.")
+		return false
+	case isIDReference(name):
+		println("Error: entry names cannot start with '#'.")
+		return false
+	}
+	if _, exists := findEntryIndex(&entries, name); exists {
+		println("Error: entry already exists.")
+		return false
+	}
+	content, ok := readNote(reader, "")
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	(*state)[group] = append(entries, LoginInfo{
+		ID:        gohash_db.NewEntryID(),
+		Kind:      gohash_db.NoteKind,
+		Name:      name,
+		Note:      content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return true
+}
+
+func editNote(info *LoginInfo, reader *bufio.Reader) bool {
+	content, ok := readNote(reader, info.Note)
+	if !ok || content == info.Note {
+		println("The note was not changed.")
+		return false
+	}
+	info.Note = content
+	info.UpdatedAt = time.Now()
+	return true
+}
+
+// readNote reads the contents of a note, either with the user's editor or line by line in the terminal.
+// The current contents are given to the editor, or kept if nothing is entered in the terminal.
+func readNote(reader *bufio.Reader, current string) (string, bool) {
+	if editor := strings.TrimSpace(os.Getenv("EDITOR")); len(editor) > 0 &&
+		yesNoQuestion(fmt.Sprintf("Do you want to write the note using your editor (%s)?", editor), reader, true) {
+		content, err := editNoteInEditor(editor, current, reader)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return "", false
 		}
-		println("Error: group already exists.")
-	} else {
+		return content, true
+	}
+
+	fmt.Printf("Enter the note, finishing with a line containing only '%s':\n", noteSentinel)
+	if len(current) > 0 {
+		println("Hint: to keep the current note, don't enter any lines.")
+	}
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			panic(err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == noteSentinel {
+			break
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return current, true
+	}
+	return strings.Join(lines, "\n") + "\n", true
+}
+
+// editNoteInEditor lets the user edit content with the given editor, returning the edited content.
+//
+// The content is written to a file only readable by the user, in a memory-backed location if possible
+// (see secureTempDir), which is overwritten with zeros and removed after the editor exits.
+func editNoteInEditor(editor, content string, reader *bufio.Reader) (string, error) {
+	// editors may create other files next to the note (e.g. swap files), so use a private directory
+	dir, secure, err := secureTempDir()
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	if !secure {
+		fmt.Printf("Warning: no memory-backed temporary directory was found, the note will be "+
+			"temporarily written to %s.\n", dir)
+		if !yesNoQuestion("Do you want to continue?", reader, false) {
+			return "", errors.New("the note was not edited")
+		}
+	}
+
+	path := filepath.Join(dir, "note.txt")
+	if err = ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", err
+	}
+	defer wipeFile(path)
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("unable to run editor: %s", err.Error())
+	}
+	edited, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
+
+// secureTempDir creates a new temporary directory only accessible by the user, and reports whether the
+// directory is memory-backed, so that files written to it never reach the disk.
+func secureTempDir() (string, bool, error) {
+	for _, parent := range []string{"/dev/shm", os.Getenv("XDG_RUNTIME_DIR")} {
+		if len(parent) == 0 {
+			continue
+		}
+		if dir, err := ioutil.TempDir(parent, "go-hash"); err == nil {
+			return dir, true, nil
+		}
+	}
+	dir, err := ioutil.TempDir("", "go-hash")
+	return dir, false, err
+}
+
+// wipeFile overwrites the contents of the file at path with zeros, then removes it.
+func wipeFile(path string) {
+	if file, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		if stat, err := file.Stat(); err == nil {
+			file.Write(make([]byte, stat.Size()))
+			file.Sync()
+		}
+		file.Close()
+	}
+	os.Remove(path)
+}
+
+// ============= Attachment helper functions ============= //
+
+func addAttachment(info *LoginInfo, state *State, reader *bufio.Reader) bool {
+	path, err := homedir.Expand(read(reader, "Enter the path of the file to attach: "))
+	if err != nil || len(path) == 0 {
+		println("Error: invalid path.")
+		return false
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return false
+	}
+	if !stat.Mode().IsRegular() {
+		println("Error: only regular files can be attached.")
+		return false
+	}
+
+	name := filepath.Base(path)
+	if answer := read(reader, fmt.Sprintf("Enter the name of the attachment (%s): ", name)); len(answer) > 0 {
+		name = answer
+	}
+	replacedSize := 0
+	if existing, exists := info.Attachment(name); exists {
+		if !yesNoQuestion("The entry already has an attachment called '"+name+"'. Do you want to replace it?",
+			reader, false) {
+			return false
+		}
+		replacedSize = len(existing.Data)
+	}
+	// check the size before reading the file, so that huge files are never read into memory
+	if err = state.CheckAttachmentSize(name, int(stat.Size())-replacedSize); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return false
+	}
+
+	now := time.Now()
+	info.SetAttachment(gohash_db.Attachment{Name: name, Data: data, AddedAt: now})
+	info.UpdatedAt = now
+	fmt.Printf("Attached %s to entry '%s' as '%s'.\n", gohash_db.FormatSize(len(data)), info.Name, name)
+	return true
+}
+
+func extractAttachment(info *LoginInfo, name string, reader *bufio.Reader) {
+	attachment, ok := info.Attachment(name)
+	if !ok {
+		fmt.Printf("Error: entry '%s' does not have an attachment called '%s'.\n", info.Name, name)
+		return
+	}
+	path := filepath.Base(name)
+	if answer := read(reader, fmt.Sprintf("Enter the path of the file to write (%s): ", path)); len(answer) > 0 {
+		var err error
+		if path, err = homedir.Expand(answer); err != nil {
+			println("Error: invalid path.")
+			return
+		}
+	}
+	if _, err := os.Stat(path); err == nil &&
+		!yesNoQuestion("File '"+path+"' already exists. Do you want to overwrite it?", reader, false) {
+		return
+	}
+	if err := writePrivateFile(path, attachment.Data); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	fmt.Printf("Extracted attachment '%s' to %s\n", name, path)
+}
+
+// writePrivateFile writes data to the file at path, making sure only the current user can read it,
+// even if the file already existed with other permissions.
+func writePrivateFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err = file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ============= OTP helper functions ============= //
+
+func setUpOTP(info *LoginInfo, reader *bufio.Reader) bool {
+	if info.OTP != nil && !yesNoQuestion("One-time passwords are already set up for this entry. "+
+		"Do you want to replace them?", reader, false) {
+		return false
+	}
+	for {
+		print("Enter the otpauth URI or the base32 secret (or just hit Enter to cancel): ")
+		text, err := terminal.ReadPassword(int(syscall.Stdin))
+		println("")
+		if err != nil {
+			panic(err)
+		}
+		if len(text) == 0 {
+			return false
+		}
+		otp, err := gohash_db.ParseOTP(string(text))
+		encryption.Zero(text)
+		if err != nil {
+			fmt.Printf("Error: %s. Please try again.\n", err.Error())
+			continue
+		}
+		info.OTP = otp
+		info.UpdatedAt = time.Now()
+		fmt.Printf("One-time passwords set up: %s\n", otp.String())
+		return true
+	}
+}
+
+// ============= Group helper functions ============= //
+
+// resolveGroup resolves the path of a group given by the user, returning the full path of the group,
+// whether it exists, and whether the path is valid.
+//
+// Paths are relative to the current group, unless they start with a '/' or the group only exists at the top-level.
+// The path of a group that does not exist is relative to the current group.
+func resolveGroup(name string, state *State, group string) (path string, exists bool, ok bool) {
+	absolute := strings.HasPrefix(name, gohash_db.GroupSeparator)
+	path, err := gohash_db.CleanGroupPath(strings.TrimPrefix(name, gohash_db.GroupSeparator))
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return "", false, false
+	}
+	if absolute || group == gohash_db.DefaultGroup {
+		return path, state.GroupExists(path), true
+	}
+	relative := group + gohash_db.GroupSeparator + path
+	switch {
+	case state.GroupExists(relative):
+		return relative, true, true
+	case state.GroupExists(path):
+		return path, true, true
+	default:
+		return relative, false, true
+	}
+}
+
+// createGroup creates a new group (and its parent groups), given its full path, returning the group
+// that should become the current group, and whether the state was changed.
+func createGroup(path string, state *State, group string) (string, bool) {
+	if len(path) == 0 {
 		println("Error: please provide a name for the group.")
+		return group, false
 	}
-	return group
+	if state.GroupExists(path) {
+		println("Error: group already exists.")
+		return group, false
+	}
+	state.AddGroup(path)
+	return path, true
 }
 
-func renameGroup(name string, state *State, group string, reader *bufio.Reader) string {
-	if len(name) > 0 {
-		entries, ok := (*state)[name]
-		if ok {
-			var newGroupName string
-			for {
-				newGroupName = read(reader, "Enter a new name for the group: ")
-				if len(newGroupName) > 0 {
-					_, exists := (*state)[newGroupName]
-					if exists {
-						println("Error: name already taken.")
-					} else {
-						break
-					}
-				} else {
-					println("Error: no name provided.")
-				}
-			}
-			if name == "default" {
-				(*state)["default"] = []LoginInfo{}
-			} else {
-				delete(*state, name)
-			}
-			(*state)[newGroupName] = entries
-			if name == group {
-				return newGroupName
+func renameGroup(name string, state *State, group string, reader *bufio.Reader) (string, bool) {
+	if len(name) == 0 {
+		println("Error: please provide the name of the group to be renamed.")
+		return group, false
+	}
+	path, exists, ok := resolveGroup(name, state, group)
+	if !ok {
+		return group, false
+	}
+	if !exists {
+		println("Error: Group does not exist.")
+		return group, false
+	}
+	for {
+		newName := read(reader, "Enter a new name for the group (start with '/' to move it to another group): ")
+		if len(newName) == 0 {
+			println("Error: no name provided.")
+			continue
+		}
+		// the new name is relative to the parent group, unless it's an absolute path
+		newPath, err := gohash_db.CleanGroupPath(strings.TrimPrefix(newName, gohash_db.GroupSeparator))
+		if err == nil && !strings.HasPrefix(newName, gohash_db.GroupSeparator) &&
+			gohash_db.ParentGroup(path) != gohash_db.DefaultGroup {
+			newPath = gohash_db.ParentGroup(path) + gohash_db.GroupSeparator + newPath
+		}
+		if err == nil {
+			err = state.RenameGroup(path, newPath)
+		}
+		if err != nil {
+			fmt.Printf("Error: %s.\n", err.Error())
+			continue
+		}
+		if path == gohash_db.DefaultGroup {
+			if group == path {
+				// the entries of the current group were moved
+				return newPath, true
 			}
-		} else {
-			println("Error: Group does not exist.")
+		} else if gohash_db.IsInGroup(group, path) {
+			// the current group was moved
+			return newPath + group[len(path):], true
 		}
-	} else {
-		println("Error: please provide the name of the group to be renamed.")
+		return group, true
 	}
-	return group
 }
 
-func removeGroup(groupName string, state *State, group string, reader *bufio.Reader) string {
+func removeGroup(groupName string, state *State, group string, reader *bufio.Reader) (string, bool) {
 	if len(groupName) == 0 {
 		println("Error: please provide the name of the group to remove.")
-	} else {
-		entries, ok := (*state)[groupName]
-		entriesLen := len(entries)
-		if ok {
-			goAhead := entriesLen == 0 // if there are no entries, don't bother asking for confirmation
-			if groupName == "default" {
-				if !goAhead {
-					goAhead = yesNoQuestion(fmt.Sprintf("Are you sure you want to remove all (%d) entries of the default group?",
-						entriesLen), reader, false)
-					if goAhead {
-						(*state)[groupName] = []LoginInfo{}
-					}
-				} else {
-					println("Warning: cannot delete the default group and there are no entries to remove.")
-				}
-			} else {
-				if !goAhead {
-					goAhead = yesNoQuestion(fmt.Sprintf("Are you sure you want to remove group '%s' and all of its (%d) entries?",
-						groupName, entriesLen), reader, false)
-					if !goAhead {
-						println("Aborted!")
-					}
-				}
-				if goAhead {
-					delete(*state, groupName)
-					if group == groupName {
-						return "default" // exit the deleted group
-					}
-				}
-			}
-		} else {
-			println("Error: group does not exist.")
+		return group, false
+	}
+	path, exists, ok := resolveGroup(groupName, state, group)
+	if !ok {
+		return group, false
+	}
+	if !exists {
+		println("Error: group does not exist.")
+		return group, false
+	}
+	if path == gohash_db.DefaultGroup {
+		entriesLen := len((*state)[path])
+		if entriesLen == 0 {
+			println("Warning: cannot delete the default group and there are no entries to remove.")
+		} else if yesNoQuestion(fmt.Sprintf("Are you sure you want to remove all (%d) entries of the default group?",
+			entriesLen), reader, false) {
+			state.DeleteGroup(path, time.Now())
+			println("Entries moved to the trash. Type 'trash' to restore them.")
+			return group, true
 		}
+		return group, false
+	}
+
+	// if there are no entries, don't bother asking for confirmation
+	entriesLen := state.CountEntries(path)
+	if entriesLen > 0 && !yesNoQuestion(fmt.Sprintf(
+		"Are you sure you want to remove group '%s', its sub-groups, and all of their (%d) entries?",
+		path, entriesLen), reader, false) {
+		println("Aborted!")
+		return group, false
 	}
-	return group
+	if state.DeleteGroup(path, time.Now()) > 0 {
+		println("Entries moved to the trash. Type 'trash' to restore them.")
+	}
+	if gohash_db.IsInGroup(group, path) {
+		return gohash_db.ParentGroup(path), true // exit the deleted group
+	}
+	return group, true
 }
 
-func groupDescription(name string, entries *[]LoginInfo, tabularFormat bool) string {
+// groupDescription describes a group, including the number of entries and sub-groups it contains.
+func groupDescription(path string, state *State, tabularFormat bool) string {
 	var entriesSize string
-	entriesLen := len(*entries)
+	entriesLen := len((*state)[path])
 	switch entriesLen {
 	case 0:
 		entriesSize = "empty"
@@ -1002,11 +3437,66 @@ func groupDescription(name string, entries *[]LoginInfo, tabularFormat bool) str
 	default:
 		entriesSize = fmt.Sprintf("%d entries", entriesLen)
 	}
+	if path != gohash_db.DefaultGroup {
+		switch subGroups := len(state.SubGroups(path)); subGroups {
+		case 0:
+		case 1:
+			entriesSize += ", 1 sub-group"
+		default:
+			entriesSize += fmt.Sprintf(", %d sub-groups", subGroups)
+		}
+	}
 	template := "%-16s (%s)"
 	if !tabularFormat {
 		template = "%s (%s)"
 	}
-	return fmt.Sprintf(template, name, entriesSize)
+	return fmt.Sprintf(template, path, entriesSize)
+}
+
+// ============= Output helper functions ============= //
+
+// parseOutputOptions parses the '-o <format>' and '--with-secrets' options given before the other
+// arguments of a command, returning the output options and the remaining arguments.
+func parseOutputOptions(args string, defaults outputOptions) (output outputOptions, rest string, ok bool) {
+	output, rest = defaults, args
+	for {
+		switch {
+		case rest == "-o" || strings.HasPrefix(rest, "-o "):
+			parts := splitTrimN(strings.TrimSpace(rest[2:]), 2)
+			format, err := gohash_db.ParseOutputFormat(parts[0])
+			if err != nil {
+				fmt.Printf("Error: %s.\n", err.Error())
+				return output, rest, false
+			}
+			output.format, rest = format, parts[1]
+		case rest == "--with-secrets" || strings.HasPrefix(rest, "--with-secrets "):
+			output.withSecrets = true
+			rest = strings.TrimSpace(rest[len("--with-secrets"):])
+		default:
+			return output, rest, true
+		}
+	}
+}
+
+// writeDocument prints a document describing groups or entries in the JSON or YAML format.
+func writeDocument(format gohash_db.OutputFormat, document interface{}) {
+	if err := gohash_db.WriteOutput(os.Stdout, format, document); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+	}
+}
+
+// writeEntryDocument prints the document describing the entry referred to by ref (see findEntry).
+func writeEntryDocument(ref string, state *State, group string, output outputOptions) {
+	entryGroup, entryIndex, err := findEntry(state, group, ref)
+	if err == gohash_db.ErrEntryNotFound {
+		fmt.Printf("Error: entry '%s' does not exist.\n", ref)
+		return
+	} else if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		return
+	}
+	writeDocument(output.format,
+		gohash_db.NewEntryDocument(entryGroup, &(*state)[entryGroup][entryIndex], output.withSecrets))
 }
 
 // ============= Goto helper functions ============= //
@@ -1034,8 +3524,97 @@ func open(url string) error {
 	return exec.Command(cmd, args...).Start()
 }
 
+// ============= KDF helper functions ============= //
+
+func showKDFParams(params *encryption.KDFParams) {
+	fmt.Printf("  %-16s %s\n", "function:", params.KDF)
+	fmt.Printf("  %-16s %d\n", "time:", params.Time)
+	fmt.Printf("  %-16s %d MiB\n", "memory:", params.Memory/1024)
+	fmt.Printf("  %-16s %d\n", "threads:", params.Threads)
+	fmt.Printf("  %-16s %d bytes\n", "salt length:", params.SaltLen)
+}
+
+func parseKDFParams(args string, current encryption.KDFParams) (encryption.KDFParams, error) {
+	flags := flag.NewFlagSet("kdf", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	kdfName := flags.String("a", current.KDF.String(), "")
+	time := flags.Uint("t", uint(current.Time), "")
+	memory := flags.Uint("m", uint(current.Memory/1024), "")
+	threads := flags.Uint("p", uint(current.Threads), "")
+	saltLen := flags.Uint("s", uint(current.SaltLen), "")
+
+	if err := flags.Parse(strings.Fields(args)); err != nil {
+		return current, err
+	}
+	if flags.NArg() > 0 {
+		return current, fmt.Errorf("unexpected argument: %s", flags.Arg(0))
+	}
+
+	kdf, err := encryption.ParseKDF(*kdfName)
+	if err != nil {
+		return current, err
+	}
+	if *memory > uint(encryption.MaxMemory/1024) || *threads > 255 || *saltLen > 255 {
+		return current, fmt.Errorf("parameter out of range")
+	}
+
+	params := encryption.KDFParams{
+		KDF:     kdf,
+		Time:    uint32(*time),
+		Memory:  uint32(*memory) * 1024,
+		Threads: uint8(*threads),
+		SaltLen: uint8(*saltLen),
+	}
+	return params, params.Validate()
+}
+
 // ============= Other helper functions ============= //
 
+// confirmMasterPassword asks the user for the current master password, returning it so that the caller
+// may use it and then zero it out.
+// Panics if the user fails to enter it too many times.
+func confirmMasterPassword(keyBox *sessionKeyBox) []byte {
+	attempts := 5
+	for {
+		print("Current password: ")
+		pass, err := terminal.ReadPassword(int(syscall.Stdin))
+		println("")
+		if err != nil {
+			panic(err)
+		}
+		if keyBox.key.VerifyPassword(pass) {
+			return pass
+		}
+		encryption.Zero(pass)
+		if attempts == 0 {
+			panic("Too many failed attempts.")
+		}
+		println("Error: incorrect password. Please try again.")
+		attempts--
+	}
+}
+
+// replaceSessionKey derives a new session key from the password, with the given KDF parameters.
+func replaceSessionKey(keyBox *sessionKeyBox, pass []byte, params encryption.KDFParams) bool {
+	key, err := gohash_db.NewSessionKey(pass, params)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return false
+	}
+	keyBox.replace(key)
+	return true
+}
+
+// readDatabaseWithPassword reads the database at dbPath without keeping its session key.
+func readDatabaseWithPassword(dbPath string, pass []byte) (State, error) {
+	data, key, err := gohash_db.OpenDatabase(dbPath, pass)
+	if err != nil {
+		return nil, err
+	}
+	key.Destroy()
+	return data, nil
+}
+
 func generatePassword(reader *bufio.Reader) (password string) {
 	var charRange []uint8
 	passwordLength := 16
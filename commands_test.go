@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/stretchr/testify/require"
+)
+
+// editAnswers the answers to the questions asked when editing an entry: a new username, and the current
+// URL, description, password and custom fields.
+const editAnswers = "joe\n\n\nn\nn\n"
+
+func TestEditEntryKeepsAttachments(t *testing.T) {
+	addedAt := time.Unix(1500000000, 0).UTC()
+	entry := LoginInfo{ID: gohash_db.NewEntryID(), Name: "server", Username: "admin", Password: "secret"}
+	entry.SetAttachment(gohash_db.Attachment{Name: "id_rsa", Data: []byte("private key"), AddedAt: addedAt})
+	state := State{"default": {entry}}
+
+	changed := editEntry("server", &state, "default", bufio.NewReader(strings.NewReader(editAnswers)))
+
+	require.True(t, changed)
+	edited := state["default"][0]
+	require.Equal(t, "joe", edited.Username)
+	require.Equal(t, entry.ID, edited.ID)
+	require.Equal(t, []gohash_db.Attachment{{Name: "id_rsa", Data: []byte("private key"), AddedAt: addedAt}},
+		edited.Attachments)
+}
+
+func TestEditEntryKeepsOTP(t *testing.T) {
+	otp, err := gohash_db.ParseOTP("otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+	require.NoError(t, err)
+	entry := LoginInfo{ID: gohash_db.NewEntryID(), Name: "example", Username: "alice", Password: "secret", OTP: otp}
+	state := State{"default": {entry}}
+
+	changed := editEntry("example", &state, "default", bufio.NewReader(strings.NewReader(editAnswers)))
+
+	require.True(t, changed)
+	edited := state["default"][0]
+	require.Equal(t, "joe", edited.Username)
+	require.Equal(t, otp, edited.OTP)
+}
+
+func TestEditEntryKeepsTags(t *testing.T) {
+	entry := LoginInfo{ID: gohash_db.NewEntryID(), Name: "bank", Username: "alice", Password: "secret"}
+	_, err := entry.AddTag("finance")
+	require.NoError(t, err)
+	state := State{"default": {entry}}
+
+	changed := editEntry("bank", &state, "default", bufio.NewReader(strings.NewReader(editAnswers)))
+
+	require.True(t, changed)
+	require.Equal(t, []string{"finance"}, state["default"][0].Tags)
+	require.Equal(t, []gohash_db.EntryRef{{Group: "default", Index: 0}}, state.FindByTag("finance"))
+}
+
+func TestCopyEntryWithinItsGroup(t *testing.T) {
+	entry := LoginInfo{ID: gohash_db.NewEntryID(), Name: "gmail", Username: "alice", Password: "secret"}
+	state := State{"default": {entry, {ID: gohash_db.NewEntryID(), Name: "gmail (copy)"}}}
+
+	// no questions should be asked, as the copy is given a unique name
+	changed := moveOrCopyEntry("gmail", &state, "default", bufio.NewReader(strings.NewReader("")), false)
+
+	require.True(t, changed)
+	require.Len(t, state["default"], 3)
+	require.Equal(t, entry, state["default"][0])
+	copied := state["default"][2]
+	require.Equal(t, "gmail (copy 2)", copied.Name)
+	require.Equal(t, "alice", copied.Username)
+	require.NotEqual(t, entry.ID, copied.ID)
+}
+
+func TestMoveEntryOverwritingAnotherEntry(t *testing.T) {
+	moved := LoginInfo{ID: gohash_db.NewEntryID(), Name: "gmail", Username: "alice", Password: "secret"}
+	overwritten := LoginInfo{ID: gohash_db.NewEntryID(), Name: "gmail", Username: "bob", Password: "old"}
+	state := State{"default": {moved}, "work": {{Name: "aws"}, overwritten}}
+
+	changed := moveOrCopyEntry("gmail work", &state, "default", bufio.NewReader(strings.NewReader("o\n")), true)
+
+	require.True(t, changed)
+	require.Empty(t, state["default"])
+	require.Equal(t, []LoginInfo{{Name: "aws"}, moved}, state["work"])
+	require.Len(t, state[gohash_db.TrashGroup], 1)
+	trashed := state[gohash_db.TrashGroup][0]
+	require.Equal(t, overwritten.ID, trashed.ID)
+	require.Equal(t, "work", trashed.Deletion.Group)
+}
+
+func TestEntryField(t *testing.T) {
+	info := LoginInfo{ID: "3f2a", Name: "bank", Username: "alice", Password: "secret", URL: "bank.com",
+		Fields: []CustomField{{Name: "pin", Value: "1234", Secret: true}}}
+
+	for name, expected := range map[string]string{
+		"password": "secret", "username": "alice", "url": "bank.com", "id": "3f2a", "pin": "1234"} {
+		value, ok := entryField(&info, name)
+		require.True(t, ok, name)
+		require.Equal(t, expected, value, name)
+	}
+	_, ok := entryField(&info, "other")
+	require.False(t, ok)
+}
+
+func TestAddSubcommandCreatesEntriesLikeTheEntryCommand(t *testing.T) {
+	cmd := &addSubcommand{}
+	writes, err := cmd.parse([]string{"work:aws", "-u", "admin", "-url", "aws.amazon.com"})
+	require.NoError(t, err)
+	require.True(t, writes)
+	state := State{"default": {}}
+
+	changed, status := cmd.run(&state, bufio.NewReader(strings.NewReader("secret\n")), outputOptions{})
+
+	require.True(t, changed)
+	require.Equal(t, exitOK, status)
+	require.Len(t, state["work"], 1)
+	added := state["work"][0]
+	require.Regexp(t, "^[0-9a-f-]{36}$", added.ID)
+	require.Equal(t, added.UpdatedAt, added.CreatedAt)
+	expected := setEntryValues("aws", nil, entryValues{username: "admin", URL: "aws.amazon.com",
+		password: "secret"}, added.CreatedAt)
+	expected.ID = added.ID
+	require.Equal(t, expected, added)
+}
+
+func TestRenameEntryUpdatesIt(t *testing.T) {
+	updatedAt := time.Unix(1500000000, 0)
+	state := State{"default": {{ID: gohash_db.NewEntryID(), Name: "gmail", UpdatedAt: updatedAt}}}
+
+	changed := renameEntry("gmail", &state, "default", bufio.NewReader(strings.NewReader("google\n")))
+
+	require.True(t, changed)
+	renamed := state["default"][0]
+	require.Equal(t, "google", renamed.Name)
+	require.True(t, renamed.UpdatedAt.After(updatedAt))
+}
+
+func TestHOTPIsNotGeneratedInReadOnlyMode(t *testing.T) {
+	otp, err := gohash_db.ParseOTP("otpauth://hotp/Example:alice?secret=JBSWY3DPEHPK3PXP&counter=5")
+	require.NoError(t, err)
+	state := State{"default": {{ID: gohash_db.NewEntryID(), Name: "example", OTP: otp}}}
+
+	changed := otpCommand{readOnly: true}.run(&state, "default", "example", bufio.NewReader(strings.NewReader("")))
+
+	require.False(t, changed)
+	require.Equal(t, uint64(5), state["default"][0].OTP.Counter)
+}
@@ -11,13 +11,14 @@ import (
 	"math/big"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 const (
-	// TIME complexity parameter for Argon2, used by PasswordHash.
+	// TIME default complexity parameter for Argon2, used by PasswordHash.
 	TIME uint32 = 8
 
-	// MEMORY complexity parameter for Argon2, used by PasswordHash.
+	// MEMORY default complexity parameter for Argon2, in KiB, used by PasswordHash.
 	MEMORY uint32 = 32 * 1024
 
 	// SALTLEN length of salt given by GenerateSalt.
@@ -25,6 +26,9 @@ const (
 
 	// KEYLEN length of key generated by PasswordHash.
 	KEYLEN uint32 = 32 // 32-bytes keys are used with AES-256
+
+	// AEADOverhead number of bytes added by EncryptAEAD to the length of a message (nonce + tag).
+	AEADOverhead = chacha20poly1305.NonceSizeX + 16
 )
 
 // PasswordStrength specifies the desired strength of a password generated with [GeneratePassword].
@@ -81,6 +85,35 @@ func Decrypt(key, message []byte) ([]byte, error) {
 	return message, nil
 }
 
+// EncryptAEAD encrypts and authenticates a message given a 32-byte secret key, using XChaCha20-Poly1305.
+// The additionalData is authenticated but not encrypted, so it must be provided again on decryption.
+// The random nonce is prepended to the returned ciphertext.
+func EncryptAEAD(key, message, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(message)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, message, additionalData), nil
+}
+
+// DecryptAEAD verifies and decrypts a message encrypted with EncryptAEAD.
+// An error is returned, and nothing is decrypted, if the message or additionalData were tampered with.
+func DecryptAEAD(key, message, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(message) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("Invalid ciphertext")
+	}
+	nonce := message[:aead.NonceSize()]
+	return aead.Open(nil, nonce, message[aead.NonceSize():], additionalData)
+}
+
 // Hmac of the message based on the given key.
 func Hmac(key, message []byte) []byte {
 	mac := hmac.New(sha512.New, key)
@@ -168,7 +201,9 @@ func GeneratePassword(length int, characters []uint8) string {
 	return string(result)
 }
 
-// PasswordHash creates a cryptographical hash of the salted password.
+// PasswordHash creates a cryptographical hash of the salted password using Argon2i with the default
+// TIME and MEMORY parameters, as done by the older database formats.
+// Use DeriveKey to choose the parameters.
 func PasswordHash(password string, salt []byte, threads uint8) []byte {
 	return argon2.Key([]byte(password), salt, TIME, MEMORY, threads, KEYLEN)
 }
@@ -25,6 +25,30 @@ func TestPasswordHash(t *testing.T) {
 	require.Equal(t, h4, h5)
 }
 
+func TestEncryptAEAD(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	message := []byte("secret message")
+	ad := []byte("header")
+
+	encrypted, err := EncryptAEAD(key, message, ad)
+	require.NoError(t, err)
+	require.Len(t, encrypted, len(message)+AEADOverhead)
+
+	decrypted, err := DecryptAEAD(key, encrypted, ad)
+	require.NoError(t, err)
+	require.Equal(t, message, decrypted)
+
+	_, err = DecryptAEAD(key, encrypted, []byte("other header"))
+	require.Error(t, err, "Tampered additional data was not detected")
+
+	encrypted[len(encrypted)-1] ^= 1
+	_, err = DecryptAEAD(key, encrypted, ad)
+	require.Error(t, err, "Tampered ciphertext was not detected")
+
+	_, err = DecryptAEAD(GenerateRandomBytes(32), encrypted, ad)
+	require.Error(t, err, "Wrong key was not detected")
+}
+
 func TestGetPasswordCharRangeWEAK(t *testing.T) {
 	charRange := GetPasswordCharRange(WEAK)
 
@@ -0,0 +1,168 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KDF identifies a key derivation function used to hash the master password.
+type KDF uint8
+
+const (
+	// Argon2i the Argon2i key derivation function, used by databases older than GH02.
+	Argon2i KDF = iota + 1
+	// Argon2id the Argon2id key derivation function, used by default since GH02.
+	Argon2id
+)
+
+const (
+	// MinSaltLen the minimum accepted length of a salt.
+	MinSaltLen uint8 = 16
+
+	// MaxTime the maximum accepted time complexity parameter of a key derivation function.
+	//
+	// The parameters are read from the database header, and used to derive the key that authenticates the
+	// header, so they can't be trusted until then. The limits on them bound the work done to open a tampered
+	// or corrupt database, which could otherwise make go-hash run for hours or run out of memory.
+	MaxTime uint32 = 64
+
+	// MaxMemory the maximum accepted memory complexity parameter of a key derivation function, in KiB (2 GiB).
+	MaxMemory uint32 = 2 * 1024 * 1024
+
+	// MaxCost the maximum accepted product of the time and memory parameters of a key derivation function,
+	// i.e. the total amount of memory processed, in KiB (16 GiB, or 8 passes over MaxMemory).
+	MaxCost uint64 = 16 * 1024 * 1024
+
+	// minCalibrationMemory CalibrateArgon2 does not reduce the memory parameter below this value, in KiB.
+	minCalibrationMemory uint32 = 8 * 1024
+)
+
+// KDFParams are the parameters of the key derivation function used to hash a master password.
+type KDFParams struct {
+	KDF     KDF
+	Time    uint32
+	Memory  uint32 // in KiB
+	Threads uint8
+	SaltLen uint8
+}
+
+// String name of the key derivation function.
+func (kdf KDF) String() string {
+	switch kdf {
+	case Argon2i:
+		return "argon2i"
+	case Argon2id:
+		return "argon2id"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(kdf))
+}
+
+// ParseKDF parses the name of a key derivation function, as returned by KDF.String().
+func ParseKDF(name string) (KDF, error) {
+	for _, kdf := range []KDF{Argon2i, Argon2id} {
+		if kdf.String() == name {
+			return kdf, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key derivation function: %s", name)
+}
+
+// DefaultKDFParams returns the parameters used for new databases.
+func DefaultKDFParams() KDFParams {
+	return KDFParams{
+		KDF:     Argon2id,
+		Time:    TIME,
+		Memory:  MEMORY,
+		Threads: 4,
+		SaltLen: uint8(SALTLEN),
+	}
+}
+
+// String human-readable representation of KDFParams.
+func (params KDFParams) String() string {
+	return fmt.Sprintf("%s (time=%d, memory=%d KiB, threads=%d, salt length=%d)",
+		params.KDF, params.Time, params.Memory, params.Threads, params.SaltLen)
+}
+
+// Validate checks that the parameters are supported and within acceptable bounds.
+func (params KDFParams) Validate() error {
+	if params.KDF != Argon2i && params.KDF != Argon2id {
+		return errors.New("unsupported key derivation function")
+	}
+	if params.Time < 1 || params.Time > MaxTime {
+		return fmt.Errorf("time parameter must be between 1 and %d", MaxTime)
+	}
+	if params.Threads < 1 {
+		return errors.New("threads parameter must be at least 1")
+	}
+	if params.Memory < 8*uint32(params.Threads) || params.Memory > MaxMemory {
+		return fmt.Errorf("memory parameter must be between %d and %d KiB", 8*uint32(params.Threads), MaxMemory)
+	}
+	if uint64(params.Time)*uint64(params.Memory) > MaxCost {
+		return fmt.Errorf("time multiplied by memory must be at most %d KiB", MaxCost)
+	}
+	if params.SaltLen < MinSaltLen {
+		return fmt.Errorf("salt length must be at least %d", MinSaltLen)
+	}
+	return nil
+}
+
+// DeriveKey creates a cryptographical hash of the salted password using the given parameters.
+func DeriveKey(password, salt []byte, params KDFParams) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	switch params.KDF {
+	case Argon2i:
+		return argon2.Key(password, salt, params.Time, params.Memory, params.Threads, KEYLEN), nil
+	default:
+		return argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, KEYLEN), nil
+	}
+}
+
+// CalibrateArgon2 benchmarks Argon2id on the current machine and returns parameters which take approximately
+// the target time to hash a password, using at most maxMemory KiB of memory.
+//
+// Memory-hardness is preferred, so the maximum memory is used unless a single iteration takes longer than the
+// target, in which case the memory is halved until that's no longer the case.
+// The time parameter is then chosen to reach the target.
+func CalibrateArgon2(target time.Duration, maxMemory uint32) KDFParams {
+	params := DefaultKDFParams()
+	params.Time = 1
+	params.Memory = maxMemory
+	if minMemory := 8 * uint32(params.Threads); params.Memory < minMemory {
+		params.Memory = minMemory
+	} else if params.Memory > MaxMemory {
+		params.Memory = MaxMemory
+	}
+
+	salt := GenerateRandomBytes(uint32(params.SaltLen))
+	measure := func() time.Duration {
+		start := time.Now()
+		if _, err := DeriveKey([]byte("calibration password"), salt, params); err != nil {
+			panic(err)
+		}
+		return time.Since(start)
+	}
+
+	elapsed := measure()
+	for elapsed > target && params.Memory/2 >= minCalibrationMemory {
+		params.Memory /= 2
+		elapsed = measure()
+	}
+
+	if elapsed > 0 && elapsed < target {
+		iterations := target / elapsed
+		if iterations > time.Duration(MaxTime) {
+			iterations = time.Duration(MaxTime)
+		}
+		if maxIterations := time.Duration(MaxCost / uint64(params.Memory)); iterations > maxIterations {
+			iterations = maxIterations
+		}
+		params.Time = uint32(iterations)
+	}
+	return params
+}
@@ -0,0 +1,75 @@
+package encryption
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultKDFParamsAreValid(t *testing.T) {
+	require.NoError(t, DefaultKDFParams().Validate())
+}
+
+func TestInvalidKDFParams(t *testing.T) {
+	invalid := []KDFParams{
+		{KDF: 0, Time: 1, Memory: 64, Threads: 1, SaltLen: 16},
+		{KDF: Argon2id, Time: 0, Memory: 64, Threads: 1, SaltLen: 16},
+		{KDF: Argon2id, Time: MaxTime + 1, Memory: 64, Threads: 1, SaltLen: 16},
+		{KDF: Argon2id, Time: 1, Memory: 64, Threads: 0, SaltLen: 16},
+		{KDF: Argon2id, Time: 1, Memory: 15, Threads: 2, SaltLen: 16},
+		{KDF: Argon2id, Time: 1, Memory: MaxMemory + 1, Threads: 1, SaltLen: 16},
+		{KDF: Argon2id, Time: 9, Memory: MaxMemory, Threads: 1, SaltLen: 16},
+		{KDF: Argon2id, Time: 1, Memory: 64, Threads: 1, SaltLen: 15},
+	}
+	for _, params := range invalid {
+		require.Error(t, params.Validate(), "Expected params to be invalid: %s", params)
+		_, err := DeriveKey([]byte("password"), GenerateSalt(), params)
+		require.Error(t, err)
+	}
+}
+
+func TestParseKDF(t *testing.T) {
+	for _, kdf := range []KDF{Argon2i, Argon2id} {
+		parsed, err := ParseKDF(kdf.String())
+		require.NoError(t, err)
+		require.Equal(t, kdf, parsed)
+	}
+	_, err := ParseKDF("scrypt")
+	require.Error(t, err)
+}
+
+func TestDeriveKey(t *testing.T) {
+	salt := GenerateSalt()
+	params := KDFParams{KDF: Argon2i, Time: TIME, Memory: MEMORY, Threads: 4, SaltLen: 32}
+
+	// Argon2i is compatible with PasswordHash
+	h1, err := DeriveKey([]byte("userpassword"), salt, params)
+	require.NoError(t, err)
+	require.Equal(t, PasswordHash("userpassword", salt, 4), h1)
+
+	params.KDF = Argon2id
+	h2, err := DeriveKey([]byte("userpassword"), salt, params)
+	require.NoError(t, err)
+	require.Len(t, h2, int(KEYLEN))
+	require.NotEqual(t, h1, h2)
+
+	params.Time = 2
+	h3, err := DeriveKey([]byte("userpassword"), salt, params)
+	require.NoError(t, err)
+	require.NotEqual(t, h2, h3)
+}
+
+func TestCalibrateArgon2(t *testing.T) {
+	params := CalibrateArgon2(200*time.Millisecond, 4*1024)
+	require.NoError(t, params.Validate())
+	require.Equal(t, Argon2id, params.KDF)
+	require.Equal(t, uint32(4*1024), params.Memory)
+	require.True(t, params.Time >= 1, "Time parameter too low: %d", params.Time)
+
+	// the memory should be reduced when a single iteration takes longer than the target
+	params = CalibrateArgon2(time.Nanosecond, 64*1024)
+	require.NoError(t, params.Validate())
+	require.Equal(t, minCalibrationMemory, params.Memory)
+	require.Equal(t, uint32(1), params.Time)
+}
@@ -0,0 +1,41 @@
+package encryption
+
+// LockedBuffer is a buffer for secrets, such as keys derived from a password.
+//
+// When supported by the OS, the buffer is allocated outside of the Go heap and locked into memory,
+// so that it is never swapped to disk. Its contents are zeroed out when the buffer is destroyed.
+type LockedBuffer struct {
+	data   []byte
+	locked bool
+}
+
+// NewLockedBuffer allocates a new LockedBuffer of the given size.
+func NewLockedBuffer(size int) *LockedBuffer {
+	data, locked := allocLocked(size)
+	return &LockedBuffer{data: data, locked: locked}
+}
+
+// Bytes returns the contents of the buffer, which may be modified in place.
+// Returns nil after the buffer is destroyed.
+func (buffer *LockedBuffer) Bytes() []byte {
+	return buffer.data
+}
+
+// Destroy zeroes out the contents of the buffer and releases its memory.
+func (buffer *LockedBuffer) Destroy() {
+	if buffer.data == nil {
+		return
+	}
+	Zero(buffer.data)
+	if buffer.locked {
+		freeLocked(buffer.data)
+	}
+	buffer.data = nil
+}
+
+// Zero overwrites the given bytes with zeros.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
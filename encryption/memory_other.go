@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !openbsd && !netbsd
+// +build !linux,!darwin,!freebsd,!openbsd,!netbsd
+
+package encryption
+
+// allocLocked allocates memory in the Go heap, as locking memory is not supported on this OS.
+func allocLocked(size int) ([]byte, bool) {
+	return make([]byte, size), false
+}
+
+func freeLocked(data []byte) {
+}
@@ -0,0 +1,26 @@
+package encryption
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockedBuffer(t *testing.T) {
+	buffer := NewLockedBuffer(32)
+	data := buffer.Bytes()
+	require.Len(t, data, 32)
+	copy(data, GenerateRandomBytes(32))
+
+	buffer.Destroy()
+	require.Nil(t, buffer.Bytes())
+
+	// destroying twice is allowed
+	buffer.Destroy()
+}
+
+func TestZero(t *testing.T) {
+	b := []byte("secret")
+	Zero(b)
+	require.Equal(t, make([]byte, 6), b)
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd
+// +build linux darwin freebsd openbsd netbsd
+
+package encryption
+
+import (
+	"log"
+	"syscall"
+)
+
+// allocLocked allocates memory with mmap, so that it's page-aligned and not managed by the Go runtime,
+// then attempts to lock it with mlock. Falls back to the Go heap if that's not possible.
+func allocLocked(size int) ([]byte, bool) {
+	if size == 0 {
+		return make([]byte, 0), false
+	}
+	data, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		log.Printf("Unable to allocate locked memory: %s", err.Error())
+		return make([]byte, size), false
+	}
+	if err = syscall.Mlock(data); err != nil {
+		// the memory may still be used, it just can be swapped to disk
+		log.Printf("Unable to lock memory: %s", err.Error())
+	}
+	return data, true
+}
+
+func freeLocked(data []byte) {
+	_ = syscall.Munlock(data)
+	_ = syscall.Munmap(data)
+}
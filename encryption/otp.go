@@ -0,0 +1,111 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// OTPAlgorithm identifies the HMAC hash function used to generate one-time passwords.
+type OTPAlgorithm uint8
+
+const (
+	// OTPSHA1 HMAC-SHA-1, the default algorithm for one-time passwords.
+	OTPSHA1 OTPAlgorithm = iota
+	// OTPSHA256 HMAC-SHA-256.
+	OTPSHA256
+	// OTPSHA512 HMAC-SHA-512.
+	OTPSHA512
+)
+
+const (
+	// MinOTPDigits the minimum number of digits of a one-time password, as required by RFC 4226.
+	MinOTPDigits = 6
+
+	// MaxOTPDigits the maximum number of digits of a one-time password.
+	MaxOTPDigits = 10
+)
+
+// String name of the algorithm, as used in otpauth URIs.
+func (alg OTPAlgorithm) String() string {
+	switch alg {
+	case OTPSHA1:
+		return "SHA1"
+	case OTPSHA256:
+		return "SHA256"
+	case OTPSHA512:
+		return "SHA512"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(alg))
+}
+
+// ParseOTPAlgorithm parses the name of an OTP algorithm, as returned by OTPAlgorithm.String(), ignoring case.
+func ParseOTPAlgorithm(name string) (OTPAlgorithm, error) {
+	for _, alg := range []OTPAlgorithm{OTPSHA1, OTPSHA256, OTPSHA512} {
+		if strings.EqualFold(alg.String(), name) {
+			return alg, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown OTP algorithm: %s", name)
+}
+
+func (alg OTPAlgorithm) hash() (func() hash.Hash, error) {
+	switch alg {
+	case OTPSHA1:
+		return sha1.New, nil
+	case OTPSHA256:
+		return sha256.New, nil
+	case OTPSHA512:
+		return sha512.New, nil
+	}
+	return nil, errors.New("unknown OTP algorithm")
+}
+
+// HOTP generates the HMAC-based one-time password for the given counter, as specified by RFC 4226.
+func HOTP(secret []byte, counter uint64, digits int, alg OTPAlgorithm) (string, error) {
+	if digits < MinOTPDigits || digits > MaxOTPDigits {
+		return "", fmt.Errorf("invalid number of OTP digits: %d", digits)
+	}
+	newHash, err := alg.hash()
+	if err != nil {
+		return "", err
+	}
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+	mac := hmac.New(newHash, secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0xf
+	code := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+
+	modulo := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%modulo), nil
+}
+
+// TOTP generates the time-based one-time password for the given time, as specified by RFC 6238,
+// where period is the time step (usually 30 seconds).
+//
+// Returns the one-time password and the time remaining until the next password is generated.
+func TOTP(secret []byte, now time.Time, period time.Duration, digits int, alg OTPAlgorithm) (string, time.Duration, error) {
+	step := int64(period / time.Second)
+	if step < 1 {
+		return "", 0, fmt.Errorf("invalid OTP period: %s", period)
+	}
+	unix := now.Unix()
+	counter := unix / step
+	remaining := time.Duration(step-unix%step) * time.Second
+	code, err := HOTP(secret, uint64(counter), digits, alg)
+	return code, remaining, err
+}
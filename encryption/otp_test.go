@@ -0,0 +1,82 @@
+package encryption
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTP(t *testing.T) {
+	// test vectors from RFC 4226, Appendix D
+	secret := []byte("12345678901234567890")
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range expected {
+		result, err := HOTP(secret, uint64(counter), 6, OTPSHA1)
+		require.NoError(t, err)
+		require.Equal(t, code, result, "Unexpected HOTP for counter %d", counter)
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// test vectors from RFC 6238, Appendix B
+	secrets := map[OTPAlgorithm][]byte{
+		OTPSHA1:   []byte("12345678901234567890"),
+		OTPSHA256: []byte("12345678901234567890123456789012"),
+		OTPSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	examples := []struct {
+		time  int64
+		codes map[OTPAlgorithm]string
+	}{
+		{59, map[OTPAlgorithm]string{OTPSHA1: "94287082", OTPSHA256: "46119246", OTPSHA512: "90693936"}},
+		{1111111109, map[OTPAlgorithm]string{OTPSHA1: "07081804", OTPSHA256: "68084774", OTPSHA512: "25091201"}},
+		{1111111111, map[OTPAlgorithm]string{OTPSHA1: "14050471", OTPSHA256: "67062674", OTPSHA512: "99943326"}},
+		{1234567890, map[OTPAlgorithm]string{OTPSHA1: "89005924", OTPSHA256: "91819424", OTPSHA512: "93441116"}},
+		{2000000000, map[OTPAlgorithm]string{OTPSHA1: "69279037", OTPSHA256: "90698825", OTPSHA512: "38618901"}},
+		{20000000000, map[OTPAlgorithm]string{OTPSHA1: "65353130", OTPSHA256: "77737706", OTPSHA512: "47863826"}},
+	}
+	for _, example := range examples {
+		for alg, code := range example.codes {
+			result, _, err := TOTP(secrets[alg], time.Unix(example.time, 0), 30*time.Second, 8, alg)
+			require.NoError(t, err)
+			require.Equal(t, code, result, "Unexpected TOTP (%s) for time %d", alg, example.time)
+		}
+	}
+}
+
+func TestTOTPRemainingTime(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	_, remaining, err := TOTP(secret, time.Unix(59, 0), 30*time.Second, 6, OTPSHA1)
+	require.NoError(t, err)
+	require.Equal(t, 1*time.Second, remaining)
+	_, remaining, err = TOTP(secret, time.Unix(60, 0), 30*time.Second, 6, OTPSHA1)
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, remaining)
+}
+
+func TestInvalidOTPParams(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	_, err := HOTP(secret, 0, 5, OTPSHA1)
+	require.Error(t, err)
+	_, err = HOTP(secret, 0, 6, OTPAlgorithm(9))
+	require.Error(t, err)
+	_, _, err = TOTP(secret, time.Now(), 0, 6, OTPSHA1)
+	require.Error(t, err)
+}
+
+func TestParseOTPAlgorithm(t *testing.T) {
+	for _, alg := range []OTPAlgorithm{OTPSHA1, OTPSHA256, OTPSHA512} {
+		parsed, err := ParseOTPAlgorithm(alg.String())
+		require.NoError(t, err)
+		require.Equal(t, alg, parsed)
+	}
+	parsed, err := ParseOTPAlgorithm("sha256")
+	require.NoError(t, err)
+	require.Equal(t, OTPSHA256, parsed)
+	_, err = ParseOTPAlgorithm("MD5")
+	require.Error(t, err)
+}
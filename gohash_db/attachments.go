@@ -0,0 +1,110 @@
+package gohash_db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// attachmentOverhead a generous estimate of the number of bytes used to encode an attachment,
+// besides its name and data.
+const attachmentOverhead = 64
+
+// Attachment a small file stored in an entry, such as a private key or a recovery document.
+//
+// Attachments are part of the state, so they are encrypted together with all other information
+// in the database.
+type Attachment struct {
+	Name    string
+	Data    []byte
+	AddedAt time.Time
+}
+
+// TooBigError is returned when an attachment can't be added because the database would become
+// bigger than MaxDBLength.
+type TooBigError struct {
+	// Size the size of the attachment.
+	Size int
+	// Available the maximum size an attachment could have.
+	Available int
+}
+
+func (err *TooBigError) Error() string {
+	return fmt.Sprintf("attachment is too big (%s), the database only has room for %s more",
+		FormatSize(err.Size), FormatSize(err.Available))
+}
+
+// FormatSize formats a number of bytes in a human-readable way.
+func FormatSize(size int) string {
+	switch {
+	case size == 1:
+		return "1 byte"
+	case size < 1024:
+		return fmt.Sprintf("%d bytes", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KiB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1024*1024))
+	}
+}
+
+// Attachment returns the attachment of the entry with the given name, if any.
+func (info *LoginInfo) Attachment(name string) (*Attachment, bool) {
+	for i := range info.Attachments {
+		if info.Attachments[i].Name == name {
+			return &info.Attachments[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetAttachment sets an attachment of the entry. If the entry already has an attachment with the same name,
+// it's replaced, otherwise, the attachment is added after all existing attachments.
+func (info *LoginInfo) SetAttachment(attachment Attachment) {
+	if existing, ok := info.Attachment(attachment.Name); ok {
+		*existing = attachment
+		return
+	}
+	info.Attachments = append(info.Attachments, attachment)
+}
+
+// RemoveAttachment removes the attachment with the given name from the entry, returning whether it existed.
+func (info *LoginInfo) RemoveAttachment(name string) bool {
+	for i := range info.Attachments {
+		if info.Attachments[i].Name == name {
+			info.Attachments = append(info.Attachments[:i:i], info.Attachments[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// PayloadSize returns the size the encrypted state would have if the database were written now.
+func (data *State) PayloadSize() (int, error) {
+	stateBytes, err := data.bytes()
+	if err != nil {
+		return 0, err
+	}
+	return len(stateBytes) + encryption.AEADOverhead, nil
+}
+
+// CheckAttachmentSize checks whether an attachment with the given name and size can be added to the state
+// without the database becoming bigger than MaxDBLength, returning a *TooBigError if it can't.
+//
+// If the attachment replaces an existing attachment, the size of the existing attachment should be subtracted
+// from size.
+func (data *State) CheckAttachmentSize(name string, size int) error {
+	payloadSize, err := data.PayloadSize()
+	if err != nil {
+		return err
+	}
+	available := MaxDBLength - payloadSize - len(name) - attachmentOverhead
+	if size > available {
+		if available < 0 {
+			available = 0
+		}
+		return &TooBigError{Size: size, Available: available}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package gohash_db
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentsAreStoredInTheDatabase(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/AttachmentsDB"
+	userPass := "very safe password"
+	addedAt := time.Unix(1500000000, 0).UTC()
+	entry := LoginInfo{ID: NewEntryID(), Name: "server", Username: "admin"}
+	entry.SetAttachment(Attachment{Name: "id_rsa", Data: []byte("private key"), AddedAt: addedAt})
+	entry.SetAttachment(Attachment{Name: "codes.pdf", Data: []byte{0, 1, 2, 255}, AddedAt: addedAt})
+	db := State{"default": {entry}}
+
+	params := encryption.KDFParams{KDF: encryption.Argon2id, Time: 1, Memory: 1024, Threads: 1, SaltLen: 16}
+	err := WriteDatabaseWithOptions(tmpDbPath, userPass, WriteOptions{KDF: params}, &db)
+	require.NoError(t, err)
+
+	persistedState, err := ReadDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	require.Equal(t, db, persistedState)
+
+	attachment, ok := persistedState["default"][0].Attachment("codes.pdf")
+	require.True(t, ok)
+	require.Equal(t, []byte{0, 1, 2, 255}, attachment.Data)
+}
+
+func TestDatabasesWithLargeAttachmentsCanBeRead(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/LargeAttachmentsDB"
+	defer os.Remove(tmpDbPath)
+	userPass := "very safe password"
+	entry := LoginInfo{ID: NewEntryID(), Name: "backup"}
+	entry.SetAttachment(Attachment{Name: "backup.tar", Data: encryption.GenerateRandomBytes(40 * 1000 * 1000)})
+	db := State{"default": {entry}}
+
+	params := encryption.KDFParams{KDF: encryption.Argon2id, Time: 1, Memory: 1024, Threads: 1, SaltLen: 16}
+	err := WriteDatabaseWithOptions(tmpDbPath, userPass, WriteOptions{KDF: params}, &db)
+	require.NoError(t, err)
+	stat, err := os.Stat(tmpDbPath)
+	require.NoError(t, err)
+	require.True(t, stat.Size() > 32000000, "the database should be larger than the old limit")
+
+	persistedState, err := ReadDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	require.Equal(t, db, persistedState)
+}
+
+func TestAttachments(t *testing.T) {
+	entry := LoginInfo{Name: "server"}
+	entry.SetAttachment(Attachment{Name: "a", Data: []byte("a")})
+	entry.SetAttachment(Attachment{Name: "b", Data: []byte("b")})
+
+	// replacing an attachment keeps its position
+	copied := entry.Copy()
+	entry.SetAttachment(Attachment{Name: "a", Data: []byte("new a")})
+	require.Len(t, entry.Attachments, 2)
+	require.Equal(t, "new a", string(entry.Attachments[0].Data))
+	require.Equal(t, "a", string(copied.Attachments[0].Data))
+
+	require.True(t, entry.RemoveAttachment("a"))
+	require.False(t, entry.RemoveAttachment("a"))
+	_, ok := entry.Attachment("a")
+	require.False(t, ok)
+	require.Len(t, copied.Attachments, 2)
+
+	require.Contains(t, copied.String(), "a (1 byte), b (1 byte)")
+	require.NotContains(t, (&LoginInfo{Name: "x"}).String(), "attachments:")
+}
+
+func TestAttachmentSizeIsLimited(t *testing.T) {
+	db := simpleDB()
+	require.NoError(t, db.CheckAttachmentSize("small", 1024))
+
+	err := db.CheckAttachmentSize("huge", MaxDBLength)
+	require.Error(t, err)
+	tooBig, ok := err.(*TooBigError)
+	require.True(t, ok)
+	require.Equal(t, MaxDBLength, tooBig.Size)
+	require.True(t, tooBig.Available > 0 && tooBig.Available < MaxDBLength)
+
+	// an attachment of exactly the available size fits
+	require.NoError(t, db.CheckAttachmentSize("huge", tooBig.Available))
+}
@@ -10,26 +10,139 @@ import (
 	"time"
 )
 
-// LoginInfo single entry containing login information for a particular website.
+// EntryKind the kind of an entry, which determines which information the entry holds.
+type EntryKind uint8
+
+const (
+	// LoginKind entries hold login information for a website. This is the default kind.
+	LoginKind EntryKind = iota
+
+	// NoteKind entries hold a multi-line secure note, and usually no login information.
+	NoteKind
+)
+
+func (kind EntryKind) String() string {
+	switch kind {
+	case LoginKind:
+		return "login"
+	case NoteKind:
+		return "note"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(kind))
+	}
+}
+
+// LoginInfo single entry containing login information for a particular website, or a secure note.
 type LoginInfo struct {
+	// ID immutable UUID of the entry, assigned when the entry is created (see NewEntryID).
+	ID          string
+	Kind        EntryKind
 	Name        string
 	URL         string
 	Username    string
 	Password    string
 	Description string
+	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// PasswordHistory previous passwords of the entry, most recent first (see SetPassword).
+	PasswordHistory []PasswordRecord
+	// Fields custom fields of the entry, in the order they should be displayed.
+	Fields []CustomField
+	// Note the contents of a NoteKind entry, which may have multiple lines.
+	Note string
+	// Attachments files stored in the entry, in the order they were added.
+	Attachments []Attachment
+	// OTP the information required to generate one-time passwords for the entry, if any.
+	OTP *OTP
+	// Tags the sorted, normalized tags of the entry (see AddTag).
+	Tags []string
+	// Deletion when and from where the entry was deleted, for entries in the TrashGroup.
+	Deletion *Deletion
 }
 
 // State the actual login information persisted by the database.
 type State map[string][]LoginInfo
 
 // String human-readable representation of LoginInfo.
+// The values of secret custom fields are masked, and the contents of notes are not included.
 func (info *LoginInfo) String() string {
-	return fmt.Sprintf("  %s:\n    %-16s %s\n    %-16s %s\n    %-16s %s\n    %-16s %s", info.Name,
-		"username:", info.Username,
-		"URL:", info.URL,
-		"updatedAt:", info.UpdatedAt.Format("2006-01-02 15:04:05"),
-		"description:", info.Description)
+	var result strings.Builder
+	if info.Kind == NoteKind {
+		fmt.Fprintf(&result, "  %s:\n    %-16s %s\n    %-16s %s\n    %-16s %s",
+			info.Name,
+			"note:", info.noteSummary(),
+			"createdAt:", info.CreatedAt.Format("2006-01-02 15:04:05"),
+			"updatedAt:", info.UpdatedAt.Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Fprintf(&result, "  %s:\n    %-16s %s\n    %-16s %s\n    %-16s %s\n    %-16s %s\n    %-16s %s",
+			info.Name,
+			"username:", info.Username,
+			"URL:", info.URL,
+			"createdAt:", info.CreatedAt.Format("2006-01-02 15:04:05"),
+			"updatedAt:", info.UpdatedAt.Format("2006-01-02 15:04:05"),
+			"description:", info.Description)
+	}
+	for i := range info.Fields {
+		field := &info.Fields[i]
+		fmt.Fprintf(&result, "\n    %-16s %s", field.Name+":", field.DisplayValue())
+	}
+	if info.OTP != nil {
+		fmt.Fprintf(&result, "\n    %-16s %s", "otp:", info.OTP.String())
+	}
+	if len(info.Tags) > 0 {
+		fmt.Fprintf(&result, "\n    %-16s %s", "tags:", strings.Join(info.Tags, ", "))
+	}
+	if len(info.Attachments) > 0 {
+		fmt.Fprintf(&result, "\n    %-16s %s", "attachments:", info.attachmentsSummary())
+	}
+	fmt.Fprintf(&result, "\n    %-16s %s", "id:", info.ID)
+	return result.String()
+}
+
+func (info *LoginInfo) noteSummary() string {
+	lines := 0
+	if len(info.Note) > 0 {
+		lines = strings.Count(strings.TrimSuffix(info.Note, "\n"), "\n") + 1
+	}
+	if lines == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", lines)
+}
+
+func (info *LoginInfo) attachmentsSummary() string {
+	summaries := make([]string, len(info.Attachments))
+	for i := range info.Attachments {
+		attachment := &info.Attachments[i]
+		summaries[i] = fmt.Sprintf("%s (%s)", attachment.Name, FormatSize(len(attachment.Data)))
+	}
+	return strings.Join(summaries, ", ")
+}
+
+// Copy returns a copy of the entry which can be modified without affecting this entry.
+func (info LoginInfo) Copy() LoginInfo {
+	if info.PasswordHistory != nil {
+		info.PasswordHistory = append([]PasswordRecord{}, info.PasswordHistory...)
+	}
+	if info.Fields != nil {
+		info.Fields = append([]CustomField{}, info.Fields...)
+	}
+	if info.Attachments != nil {
+		// the data of attachments is never modified, only replaced, so it can be shared
+		info.Attachments = append([]Attachment{}, info.Attachments...)
+	}
+	if info.Tags != nil {
+		info.Tags = append([]string{}, info.Tags...)
+	}
+	if info.OTP != nil {
+		otp := *info.OTP
+		info.OTP = &otp
+	}
+	if info.Deletion != nil {
+		deletion := *info.Deletion
+		info.Deletion = &deletion
+	}
+	return info
 }
 
 func (info *LoginInfo) bytes() []byte {
@@ -84,6 +197,14 @@ func decodeLoginInfo(info []byte) (LoginInfo, error) {
 }
 
 // Encode the state into Go's serialization format.
+// stateFormat describes how the state was encoded. It's encoded after the state, so older versions of go-hash,
+// which only decode the state, can still read it.
+type stateFormat struct {
+	// NestedGroups whether the state may have nested groups. States written before groups could be nested
+	// may have group names containing the GroupSeparator (see migrateFlatGroups).
+	NestedGroups bool
+}
+
 func (data *State) bytes() ([]byte, error) {
 	stateBuffer := bytes.Buffer{}
 	gobEncoder := gob.NewEncoder(&stateBuffer)
@@ -91,6 +212,10 @@ func (data *State) bytes() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = gobEncoder.Encode(stateFormat{NestedGroups: true})
+	if err != nil {
+		return nil, err
+	}
 	return stateBuffer.Bytes(), nil
 }
 
@@ -104,5 +229,9 @@ func decodeState(stateBytes []byte) (State, error) {
 	if err != nil {
 		return nil, err
 	}
+	var format stateFormat
+	if err = gobDecoder.Decode(&format); err != nil || !format.NestedGroups {
+		migrateFlatGroups(data)
+	}
 	return data, nil
 }
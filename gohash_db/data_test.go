@@ -0,0 +1,26 @@
+package gohash_db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteContentsAreNotDisplayed(t *testing.T) {
+	note := LoginInfo{Name: "recovery codes", Kind: NoteKind, Note: "code-1\ncode-2\ncode-3\n"}
+	text := note.String()
+	require.Contains(t, text, "recovery codes:")
+	require.Contains(t, text, "3 lines")
+	require.NotContains(t, text, "code-")
+	require.NotContains(t, text, "username:")
+
+	note.Note = "single line"
+	require.Contains(t, note.String(), "1 line")
+}
+
+func TestEntryKindDefaultsToLogin(t *testing.T) {
+	db := simpleDB()
+	require.Equal(t, LoginKind, db["default"][0].Kind)
+	require.Equal(t, "login", LoginKind.String())
+	require.Equal(t, "note", NoteKind.String())
+}
@@ -1,262 +1,276 @@
 package gohash_db
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"log"
 	"os"
-	"runtime"
 
 	"github.com/renatoathaydes/go-hash/encryption"
 )
 
 const (
 	// DBVersion is the current version of the go-hash database format.
-	DBVersion = "GH01"
+	DBVersion = "GH02"
 
-	// PrevDBVersion is the previous version of the database format. go-hash automatically migrates
-	// databases from its previous version.
-	PrevDBVersion = "GH00"
+	// DBVersionGH01 is the previous version of the database format, which did not use authenticated encryption.
+	// go-hash automatically migrates databases from this version.
+	DBVersionGH01 = "GH01"
 
-	// MinDBLength      V | S  | B1 | B2 | B3 | B4 | MAC| E
-	MinDBLength = 4 + 32 + 32 + 32 + 32 + 32 + 32 + 4
+	// DBVersionGH00 is the first version of the database format, which used a machine-dependent number of threads
+	// to hash the master password. go-hash automatically migrates databases from this version.
+	DBVersionGH00 = "GH00"
+
+	// MinDBLength      V | KDF params    | S                     | B                | E
+	MinDBLength = 4 + kdfParamsLength + int(encryption.MinSaltLen) + wrappedKeyLength + encryption.AEADOverhead
 
 	// MaxDBLength the maximum allowed size of a database
 	MaxDBLength = 64 * 1000 * 1024
 
-	// Argon2Threads the fixed number of threads to use for the Argon2 Hash function.
+	// Argon2Threads the fixed number of threads used by the Argon2 Hash function in the GH01 version.
 	Argon2Threads uint8 = 4
+
+	// wrappedKeyLength the length of the B block (the encrypted K key)
+	wrappedKeyLength = 32 + encryption.AEADOverhead
+
+	// maxFileLength the maximum size of a database file: a database of MaxDBLength, with the longest header
+	// and the B block (older formats have shorter headers).
+	maxFileLength = 4 + kdfParamsLength + 255 + wrappedKeyLength + MaxDBLength
 )
 
-// WriteDatabase writes the encrypted database to the given filePath with the provided state and key.
-func WriteDatabase(filePath, password string, data *State) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// WriteOptions options for writing a database.
+type WriteOptions struct {
+	// KDF the parameters of the key derivation function used to hash the master password.
+	KDF encryption.KDFParams
 
-	stateBytes, err := data.bytes()
-	if err != nil {
-		return err
-	}
+	// Backups the number of previous versions of the database file to keep (see BackupPath).
+	Backups int
+}
 
-	salt := encryption.GenerateSalt()
-	log.Printf("Writing salt: %x", salt)
-	P := encryption.PasswordHash(password, salt, Argon2Threads)
-	log.Printf("Calculated P: %x", P)
+// DefaultWriteOptions returns the options used by WriteDatabase.
+func DefaultWriteOptions() WriteOptions {
+	return WriteOptions{KDF: encryption.DefaultKDFParams(), Backups: DefaultBackups}
+}
 
-	K := encryption.GenerateRandomBytes(32)
-	L := encryption.GenerateRandomBytes(32)
-	log.Printf("K = %x", K)
-	log.Printf("L = %x", L)
+// WriteDatabase writes the encrypted database to the given filePath with the provided state and key,
+// using the default options.
+//
+// The database is always written with the current version of the database format, DBVersion.
+func WriteDatabase(filePath, password string, data *State) error {
+	return WriteDatabaseWithOptions(filePath, password, DefaultWriteOptions(), data)
+}
 
-	B1, err := encryption.Encrypt(P, K[:16])
+// WriteDatabaseWithOptions writes the encrypted database to the given filePath with the provided state, key
+// and options.
+//
+// The master password is hashed with a new salt. To save a database several times, prefer using
+// WriteDatabaseWithKey, which avoids hashing the password again.
+func WriteDatabaseWithOptions(filePath, password string, options WriteOptions, data *State) error {
+	key, err := NewSessionKey([]byte(password), options.KDF)
 	if err != nil {
 		return err
 	}
-	log.Printf("Writing B1 = %x", B1)
-	B2, err := encryption.Encrypt(P, K[16:])
-	if err != nil {
-		return err
-	}
-	log.Printf("Writing B2 = %x", B2)
-	B3, err := encryption.Encrypt(P, L[:16])
-	if err != nil {
-		return err
-	}
-	log.Printf("Writing B3 = %x", B3)
-	B4, err := encryption.Encrypt(P, L[16:])
+	defer key.Destroy()
+	return WriteDatabaseWithKey(filePath, key, options.Backups, data)
+}
+
+// WriteDatabaseWithKey writes the encrypted database to the given filePath with the provided state,
+// using a SessionKey instead of the master password, and keeping the given number of backups.
+//
+// The database is first written to a temporary file, which then atomically replaces the existing file,
+// if any, so that the existing database is never left partially written.
+func WriteDatabaseWithKey(filePath string, key *SessionKey, backups int, data *State) error {
+	stateBytes, err := data.bytes()
 	if err != nil {
 		return err
 	}
-	log.Printf("Writing B4 = %x", B4)
 
-	encryptedState, err := encryption.Encrypt(K, stateBytes)
+	encryptedState, err := encryption.EncryptAEAD(key.dataKey(), stateBytes, key.header)
 	if err != nil {
 		return err
 	}
 
 	if len(encryptedState) > MaxDBLength {
-		return errors.New("database too big! Cannot save it to avoid file bomb attacks. Please remove entries you don't need")
+		return errors.New("database too big! Cannot save it to avoid file bomb attacks. Please remove entries or attachments you don't need")
 	}
 
-	mac := encryption.Hmac(L, append(salt, stateBytes...))
-	log.Printf("Generated HMAC with length %d", len(mac))
-
-	fileOffset := 0
-
-	// version | salt | B1 | B2 | B3 | B4 | HMAC | E
-	for _, b := range [][]byte{[]byte(DBVersion), salt, B1, B2, B3, B4, mac, encryptedState} {
-		_, err = file.WriteAt(b, int64(fileOffset))
-		if err != nil {
-			return err
-		}
-		fileOffset += len(b)
+	// version | KDF params | salt | B | E
+	contents := make([]byte, 0, len(key.header)+len(key.wrappedKey)+len(encryptedState))
+	for _, b := range [][]byte{key.header, key.wrappedKey, encryptedState} {
+		contents = append(contents, b...)
 	}
-	return nil
+
+	return writeFileAtomically(filePath, contents, backups)
 }
 
 // ReadDatabase reads the encrypted database from the filePath, using the given password for decryption.
+//
+// Databases using older versions of the database format can also be read.
 func ReadDatabase(filePath string, password string) (State, error) {
-	dbError := "Corrupt database"
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	fileStat, err := file.Stat()
+	data, key, err := OpenDatabase(filePath, []byte(password))
 	if err != nil {
 		return nil, err
 	}
+	key.Destroy()
+	return data, nil
+}
 
-	// limit the size of the DB
-	if fileStat.Size() < MinDBLength || fileStat.Size() > 32000000 {
-		return nil, errors.New(dbError)
-	}
-
-	var fileOffset int64
-
-	version := make([]byte, 4, 4)
-	_, err = file.ReadAt(version, fileOffset)
+// OpenDatabase reads the encrypted database from the filePath, using the given password for decryption.
+//
+// Besides the state, returns a SessionKey which can be used to write the database again with
+// WriteDatabaseWithKey. If the database uses an older version of the database format, the SessionKey
+// uses the default KDF parameters, so that the database is upgraded on the next write.
+func OpenDatabase(filePath string, password []byte) (State, *SessionKey, error) {
+	contents, err := readDatabaseFile(filePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	fileOffset += 4
-
-	var threads uint8
 
-	switch string(version) {
-	case PrevDBVersion:
-		threads = uint8(runtime.NumCPU())
-		log.Printf("Reading old version of database, threads param set to %d", threads)
+	version := string(contents[:4])
+	switch version {
 	case DBVersion:
-		threads = Argon2Threads
 		log.Printf("Database version: %s", DBVersion)
+		return readDatabase(contents, password)
+	case DBVersionGH00, DBVersionGH01:
+		log.Printf("Reading old version of database: %s", version)
+		data, err := readLegacyDatabase(contents, version, password)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := NewSessionKey(password, encryption.DefaultKDFParams())
+		if err != nil {
+			return nil, nil, err
+		}
+		return data, key, nil
 	default:
-		return nil, errors.New("Unsupported database version")
+		return nil, nil, errors.New("Unsupported database version")
 	}
+}
 
-	log.Println("Reading salt")
-	salt := make([]byte, 32, 32)
-	_, err = file.ReadAt(salt, fileOffset)
+// ReadDatabaseWithKey reads the encrypted database from the filePath, using a SessionKey for decryption.
+//
+// ErrKeyMismatch is returned if the database was not written with the master password, salt and KDF
+// parameters of the key.
+func ReadDatabaseWithKey(filePath string, key *SessionKey) (State, error) {
+	contents, err := readDatabaseFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	fileOffset += 32
-	log.Println("Salt read successfully, calculating P.")
-
-	P := encryption.PasswordHash(password, salt, threads)
-	log.Printf("Calculated P, reading Bs. P = %x", P)
-
-	B1 := make([]byte, 32, 32)
-	_, err = file.ReadAt(B1, fileOffset)
+	header, headerLength, err := decodeHeader(contents)
 	if err != nil {
 		return nil, err
 	}
-	fileOffset += 32
-	log.Printf("Read B1: %x", B1)
+	if header.Version != DBVersion || !bytes.Equal(contents[:headerLength], key.header) {
+		return nil, ErrKeyMismatch
+	}
+	data, _, err := decryptDatabase(contents, headerLength, key.passwordHash())
+	return data, err
+}
 
-	B2 := make([]byte, 32, 32)
-	_, err = file.ReadAt(B2, fileOffset)
+const dbError = "Corrupt database"
+
+// readDatabaseFile reads the contents of a database file, checking its size is acceptable.
+func readDatabaseFile(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	fileOffset += 32
-	log.Printf("Read B2: %x", B2)
+	defer file.Close()
 
-	B3 := make([]byte, 32, 32)
-	_, err = file.ReadAt(B3, fileOffset)
+	fileStat, err := file.Stat()
 	if err != nil {
 		return nil, err
 	}
-	fileOffset += 32
-	log.Printf("Read B3: %x", B3)
 
-	B4 := make([]byte, 32, 32)
-	_, err = file.ReadAt(B4, fileOffset)
-	if err != nil {
-		return nil, err
+	// limit the size of the DB
+	if fileStat.Size() < int64(MinDBLength) || fileStat.Size() > maxFileLength {
+		return nil, errors.New(dbError)
 	}
-	fileOffset += 32
-	log.Printf("Read B4: %x", B4)
 
-	decryptedB1, err := encryption.Decrypt(P, B1)
+	contents := make([]byte, fileStat.Size())
+	_, err = io.ReadFull(file, contents)
 	if err != nil {
 		return nil, err
 	}
-	log.Println("Decrypted B1")
-	decryptedB2, err := encryption.Decrypt(P, B2)
+	return contents, nil
+}
+
+func readDatabase(contents []byte, password []byte) (State, *SessionKey, error) {
+	header, headerLength, err := decodeHeader(contents)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	log.Println("Decrypted B2")
+	log.Printf("Database KDF: %s", header.KDF)
 
-	decryptedB3, err := encryption.Decrypt(P, B3)
+	P, err := encryption.DeriveKey(password, header.Salt, header.KDF)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	log.Println("Decrypted B3")
+	defer encryption.Zero(P)
 
-	decryptedB4, err := encryption.Decrypt(P, B4)
+	data, K, err := decryptDatabase(contents, headerLength, P)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	log.Println("Decrypted B4")
+	defer encryption.Zero(K)
 
-	K := append(decryptedB1, decryptedB2...)
-	L := append(decryptedB3, decryptedB4...)
-
-	log.Printf("Got K=%x", K)
-	log.Printf("Got L=%x", L)
-	log.Printf("Reading HMAC")
+	B := contents[headerLength : headerLength+wrappedKeyLength]
+	return data, newSessionKey(contents[:headerLength], header.KDF, B, P, K), nil
+}
 
-	mac := make([]byte, 64, 64)
-	_, err = file.ReadAt(mac, fileOffset)
-	if err != nil {
-		return nil, err
+// decryptDatabase decrypts the B and E blocks of a database using P, the hash of the master password.
+// Returns the decrypted state and K, the key used to encrypt it.
+func decryptDatabase(contents []byte, headerLength int, P []byte) (State, []byte, error) {
+	if len(contents) < headerLength+wrappedKeyLength+encryption.AEADOverhead {
+		return nil, nil, errors.New(dbError)
 	}
-	fileOffset += 64
 
-	plen := fileStat.Size() - fileOffset
+	headerBytes := contents[:headerLength]
+	B := contents[headerLength : headerLength+wrappedKeyLength]
+	payload := contents[headerLength+wrappedKeyLength:]
 
-	if plen > MaxDBLength {
-		return nil, errors.New(dbError)
+	if len(payload) > MaxDBLength {
+		return nil, nil, errors.New(dbError)
 	}
 
-	log.Printf("Reading encrypted payload with len = %d", plen)
-	payload := make([]byte, plen, plen)
-	_, err = file.ReadAt(payload, fileOffset)
+	log.Printf("Decrypting B = %x", B)
+	K, err := encryption.DecryptAEAD(P, B, headerBytes)
 	if err != nil {
-		return nil, err
+		return nil, nil, errors.New("incorrect password or corrupt database")
 	}
-	fileOffset += plen
 
-	log.Printf("Decrypting payload")
-	stateBytes, err := encryption.Decrypt(K, payload)
+	log.Printf("Decrypting payload with len = %d", len(payload))
+	stateBytes, err := encryption.DecryptAEAD(K, payload, headerBytes)
 	if err != nil {
-		return nil, errors.New(dbError)
+		encryption.Zero(K)
+		return nil, nil, errors.New(dbError)
 	}
+	log.Printf("Database read successfully")
 
-	expectedMac := encryption.Hmac(L, append(salt, stateBytes...))
-
-	log.Printf("Verifying HMAC")
-	if ok := encryption.VerifyHmac(expectedMac, mac); !ok {
-		return nil, errors.New("incorrect password or corrupt database")
+	data, err := decodeAndLogState(stateBytes)
+	if err != nil {
+		encryption.Zero(K)
+		return nil, nil, err
 	}
-	log.Printf("Database read successfully")
+	return data, K, nil
+}
 
-	// decryption and validation completed successfully!
+// decodeAndLogState decodes the state of a database of any version, giving IDs and creation times to the
+// entries that don't have them, e.g. entries written by older versions of go-hash.
+func decodeAndLogState(stateBytes []byte) (State, error) {
 	data, err := decodeState(stateBytes)
 
 	if err == nil {
+		backfillEntries(data)
+		// the trash is not a group, and its entries were deleted
+		groups := data.withoutTrash()
 		entryCount := 0
-		for _, entries := range data {
+		for _, entries := range groups {
 			entryCount += len(entries)
 		}
 		log.Printf("Decoded database, found %d groups, containing %d entries",
-			len(data), entryCount)
+			len(groups), entryCount)
 	}
 	return data, err
 }
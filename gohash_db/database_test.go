@@ -1,10 +1,15 @@
 package gohash_db
 
 import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
 	"os"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/renatoathaydes/go-hash/encryption"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,9 +52,10 @@ func TestCreateAndReadDBs(t *testing.T) {
 	examples := []Ex{Ex{"SimpleDB", simpleDB()}, Ex{"EmptyDB", emptyDB()}, Ex{"LargeDB", largeDB()}}
 
 	for _, example := range examples {
-		t.Logf("Testing example: %s", example)
+		t.Logf("Testing example: %s", example.name)
 		tmpDbPath := os.TempDir() + "/" + example.name
 		userPass := "very safe password"
+		backfillEntries(example.db)
 		err := WriteDatabase(tmpDbPath, userPass, &example.db)
 		require.NoError(t, err, "Error writing database %s", example.name)
 		persistedState, err := ReadDatabase(tmpDbPath, userPass)
@@ -57,3 +63,197 @@ func TestCreateAndReadDBs(t *testing.T) {
 		require.Equal(t, example.db, persistedState, "The restored State (%s) is not as expected", example.name)
 	}
 }
+
+func TestReadAndUpgradeLegacyDBs(t *testing.T) {
+	for _, version := range []string{DBVersionGH00, DBVersionGH01} {
+		t.Logf("Testing legacy version: %s", version)
+		tmpDbPath := os.TempDir() + "/LegacyDB" + version
+		userPass := "very safe password"
+		db := largeDB()
+		err := writeLegacyDatabase(tmpDbPath, version, userPass, &db)
+		require.NoError(t, err, "Error writing legacy database %s", version)
+		header, err := ReadHeader(tmpDbPath)
+		require.NoError(t, err)
+		require.Equal(t, version, header.Version)
+		require.Equal(t, encryption.Argon2i, header.KDF.KDF)
+		persistedState, err := ReadDatabase(tmpDbPath, userPass)
+		require.NoError(t, err, "Error reading legacy database: %s", version)
+
+		// entries of legacy databases are given IDs and creation times when migrated
+		backfillEntries(db)
+		require.NotEmpty(t, persistedState["default"][0].ID)
+		require.Equal(t, db, persistedState, "The restored State (%s) is not as expected", version)
+
+		// the next write upgrades the database to the current version
+		err = WriteDatabase(tmpDbPath, userPass, &persistedState)
+		require.NoError(t, err, "Error upgrading legacy database %s", version)
+		contents, err := ioutil.ReadFile(tmpDbPath)
+		require.NoError(t, err)
+		require.Equal(t, DBVersion, string(contents[:4]))
+		header, err = ReadHeader(tmpDbPath)
+		require.NoError(t, err)
+		require.Equal(t, encryption.DefaultKDFParams(), header.KDF)
+		upgradedState, err := ReadDatabase(tmpDbPath, userPass)
+		require.NoError(t, err, "Error reading upgraded database: %s", version)
+		require.Equal(t, db, upgradedState, "The upgraded State (%s) is not as expected", version)
+	}
+}
+
+func TestReadLegacyDBWithSlashesInGroupNames(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/LegacyGroupsDB"
+	userPass := "very safe password"
+	// before groups could be nested, group names could contain anything
+	db := State{
+		"default": {{Name: "google"}},
+		"a/b":     {{Name: "github"}},
+		"a-b":     {{Name: "gitlab"}},
+		"x//y":    {{Name: "aws"}},
+		" ":       {{Name: "vpn"}},
+	}
+	err := writeLegacyDatabase(tmpDbPath, DBVersionGH01, userPass, &db)
+	require.NoError(t, err)
+
+	persistedState, err := ReadDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a-b", "a-b (2)", "default", "unnamed", "x--y"}, persistedState.SubGroups(DefaultGroup))
+	require.Equal(t, "gitlab", persistedState["a-b"][0].Name)
+	require.Equal(t, "github", persistedState["a-b (2)"][0].Name)
+	require.Equal(t, "aws", persistedState["x--y"][0].Name)
+	require.Equal(t, "vpn", persistedState["unnamed"][0].Name)
+	for group := range persistedState {
+		clean, err := CleanGroupPath(group)
+		require.NoError(t, err)
+		require.Equal(t, group, clean)
+	}
+
+	// once written in the current format, groups are no longer migrated
+	persistedState.AddGroup("work/aws")
+	err = WriteDatabase(tmpDbPath, userPass, &persistedState)
+	require.NoError(t, err)
+	upgradedState, err := ReadDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	require.Equal(t, []string{"work/aws"}, upgradedState.SubGroups("work"))
+	require.Equal(t, persistedState["a-b (2)"], upgradedState["a-b (2)"])
+}
+
+func TestReadGH02DBWithoutEntryIDs(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/NoIDsDB"
+	userPass := "very safe password"
+	// entries written by older versions of go-hash, or by other clients, may not have IDs
+	db := largeDB()
+	err := WriteDatabase(tmpDbPath, userPass, &db)
+	require.NoError(t, err)
+	header, err := ReadHeader(tmpDbPath)
+	require.NoError(t, err)
+	require.Equal(t, DBVersion, header.Version)
+
+	persistedState, err := ReadDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	backfillEntries(db)
+	require.Equal(t, db, persistedState)
+
+	id := persistedState["Work"][1].ID
+	require.Equal(t, legacyEntryID("Work", "VPN"), id)
+	group, index, err := persistedState.FindByIDPrefix(id[:8])
+	require.NoError(t, err)
+	require.Equal(t, "Work", group)
+	require.Equal(t, 1, index)
+}
+
+func TestCustomKDFParams(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/CustomKDFDB"
+	userPass := "very safe password"
+	db := largeDB()
+	backfillEntries(db)
+	params := encryption.KDFParams{KDF: encryption.Argon2i, Time: 2, Memory: 1024, Threads: 2, SaltLen: 24}
+	err := WriteDatabaseWithOptions(tmpDbPath, userPass, WriteOptions{KDF: params}, &db)
+	require.NoError(t, err)
+
+	header, err := ReadHeader(tmpDbPath)
+	require.NoError(t, err)
+	require.Equal(t, DBVersion, header.Version)
+	require.Equal(t, params, header.KDF)
+	require.Len(t, header.Salt, 24)
+
+	persistedState, err := ReadDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	require.Equal(t, db, persistedState)
+
+	params.Time = 0
+	err = WriteDatabaseWithOptions(tmpDbPath, userPass, WriteOptions{KDF: params}, &db)
+	require.Error(t, err, "Invalid KDF parameters were accepted")
+}
+
+func TestWrongPasswordIsRejected(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/WrongPasswordDB"
+	db := simpleDB()
+	err := WriteDatabase(tmpDbPath, "very safe password", &db)
+	require.NoError(t, err)
+	_, err = ReadDatabase(tmpDbPath, "wrong password")
+	require.Error(t, err)
+}
+
+func TestTamperedDBIsRejected(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/TamperedDB"
+	userPass := "very safe password"
+	db := simpleDB()
+	err := WriteDatabase(tmpDbPath, userPass, &db)
+	require.NoError(t, err)
+	original, err := ioutil.ReadFile(tmpDbPath)
+	require.NoError(t, err)
+
+	headerLength := 4 + kdfParamsLength + 32
+
+	// flip one bit in the KDF parameters, in the salt, in the wrapped key and in the payload
+	for _, index := range []int{4, 10, headerLength - 1, headerLength + 30, len(original) - 1} {
+		tampered := append([]byte{}, original...)
+		tampered[index] ^= 1
+		err = ioutil.WriteFile(tmpDbPath, tampered, 0600)
+		require.NoError(t, err)
+		_, err = ReadDatabase(tmpDbPath, userPass)
+		require.Error(t, err, "Tampering with byte %d was not detected", index)
+	}
+}
+
+// legacyStateBytes encodes the state as go-hash did before groups could be nested.
+func legacyStateBytes(data *State) ([]byte, error) {
+	stateBuffer := bytes.Buffer{}
+	err := gob.NewEncoder(&stateBuffer).Encode(data)
+	return stateBuffer.Bytes(), err
+}
+
+// writeLegacyDatabase writes a database using one of the formats that preceded GH02.
+func writeLegacyDatabase(filePath, version, password string, data *State) error {
+	stateBytes, err := legacyStateBytes(data)
+	if err != nil {
+		return err
+	}
+
+	threads := Argon2Threads
+	if version == DBVersionGH00 {
+		threads = uint8(runtime.NumCPU())
+	}
+
+	salt := encryption.GenerateSalt()
+	P := encryption.PasswordHash(password, salt, threads)
+	K := encryption.GenerateRandomBytes(32)
+	L := encryption.GenerateRandomBytes(32)
+
+	contents := append([]byte(version), salt...)
+	for _, key := range [][]byte{K[:16], K[16:], L[:16], L[16:]} {
+		B, err := encryption.Encrypt(P, key)
+		if err != nil {
+			return err
+		}
+		contents = append(contents, B...)
+	}
+
+	encryptedState, err := encryption.Encrypt(K, stateBytes)
+	if err != nil {
+		return err
+	}
+
+	contents = append(contents, encryption.Hmac(L, append(salt, stateBytes...))...)
+	contents = append(contents, encryptedState...)
+	return ioutil.WriteFile(filePath, contents, 0600)
+}
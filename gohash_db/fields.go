@@ -0,0 +1,52 @@
+package gohash_db
+
+// CustomField an arbitrary, named piece of information stored in an entry, such as an account number,
+// a security question or an API key.
+type CustomField struct {
+	Name  string
+	Value string
+	// Secret fields are masked when the entry is displayed, like passwords.
+	Secret bool
+}
+
+// maskedValue the value displayed in place of secret values.
+const maskedValue = "********"
+
+// DisplayValue returns the value of the field, masked if the field is secret.
+func (field *CustomField) DisplayValue() string {
+	if field.Secret {
+		return maskedValue
+	}
+	return field.Value
+}
+
+// Field returns the custom field of the entry with the given name, if any.
+func (info *LoginInfo) Field(name string) (*CustomField, bool) {
+	for i := range info.Fields {
+		if info.Fields[i].Name == name {
+			return &info.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetField sets a custom field of the entry. If the entry already has a field with the same name,
+// it's replaced, otherwise, the field is added after all existing fields.
+func (info *LoginInfo) SetField(field CustomField) {
+	if existing, ok := info.Field(field.Name); ok {
+		*existing = field
+		return
+	}
+	info.Fields = append(info.Fields, field)
+}
+
+// RemoveField removes the custom field with the given name from the entry, returning whether it existed.
+func (info *LoginInfo) RemoveField(name string) bool {
+	for i := range info.Fields {
+		if info.Fields[i].Name == name {
+			info.Fields = append(info.Fields[:i:i], info.Fields[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package gohash_db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomFields(t *testing.T) {
+	entry := LoginInfo{Name: "bank"}
+	entry.SetField(CustomField{Name: "account", Value: "12345"})
+	entry.SetField(CustomField{Name: "pin", Value: "9876", Secret: true})
+	entry.SetField(CustomField{Name: "question", Value: "first pet?"})
+
+	field, ok := entry.Field("pin")
+	require.True(t, ok)
+	require.Equal(t, "9876", field.Value)
+	_, ok = entry.Field("other")
+	require.False(t, ok)
+
+	// replacing a field keeps its position
+	copied := entry.Copy()
+	entry.SetField(CustomField{Name: "account", Value: "54321"})
+	require.Equal(t, []string{"account", "pin", "question"}, fieldNames(&entry))
+	require.Equal(t, "54321", entry.Fields[0].Value)
+	require.Equal(t, "12345", copied.Fields[0].Value)
+
+	require.True(t, entry.RemoveField("pin"))
+	require.False(t, entry.RemoveField("pin"))
+	require.Equal(t, []string{"account", "question"}, fieldNames(&entry))
+	require.Equal(t, []string{"account", "pin", "question"}, fieldNames(&copied))
+}
+
+func TestSecretFieldsAreMasked(t *testing.T) {
+	entry := LoginInfo{Name: "bank", Fields: []CustomField{
+		{Name: "account", Value: "12345"},
+		{Name: "pin", Value: "9876", Secret: true},
+	}}
+	text := entry.String()
+	require.Contains(t, text, "account:")
+	require.Contains(t, text, "12345")
+	require.Contains(t, text, "pin:")
+	require.NotContains(t, text, "9876")
+	require.True(t, strings.Index(text, "account:") < strings.Index(text, "pin:"))
+}
+
+func fieldNames(entry *LoginInfo) []string {
+	var names []string
+	for _, field := range entry.Fields {
+		names = append(names, field.Name)
+	}
+	return names
+}
@@ -0,0 +1,227 @@
+package gohash_db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBackups the default number of backups kept when writing a database.
+const DefaultBackups = 3
+
+// Backup a previous version of a database file.
+type Backup struct {
+	// Number of the backup, starting from 1 for the most recent backup.
+	Number  int
+	Path    string
+	ModTime time.Time
+	Size    int64
+}
+
+// BackupPath returns the path of the nth most recent backup of the database at filePath.
+//
+// For example, the most recent backup of ~/.go-hash is ~/.go-hash.bak.1.
+func BackupPath(filePath string, n int) string {
+	return filePath + ".bak." + strconv.Itoa(n)
+}
+
+// ListBackups lists the existing backups of the database at filePath, most recent first.
+func ListBackups(filePath string) ([]Backup, error) {
+	prefix := filepath.Base(filePath) + ".bak."
+	files, err := ioutil.ReadDir(filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(file.Name()[len(prefix):])
+		if err != nil || n < 1 {
+			continue
+		}
+		backups = append(backups, Backup{
+			Number:  n,
+			Path:    BackupPath(filePath, n),
+			ModTime: file.ModTime(),
+			Size:    file.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Number < backups[j].Number
+	})
+	return backups, nil
+}
+
+// FileStamp identifies the contents of a database file at some point in time,
+// so that changes made to the file by other processes can be detected.
+type FileStamp struct {
+	ModTime time.Time
+	Size    int64
+	Hash    []byte
+}
+
+// StampFile returns the current FileStamp of the file at filePath.
+func StampFile(filePath string) (FileStamp, error) {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return FileStamp{}, err
+	}
+	hash, err := hashFile(filePath)
+	if err != nil {
+		return FileStamp{}, err
+	}
+	return FileStamp{ModTime: stat.ModTime(), Size: stat.Size(), Hash: hash}, nil
+}
+
+// HasChanged checks whether the contents of the file at filePath are different from when the stamp was taken.
+// A file that no longer exists is not considered to have changed, as there's nothing that could be overwritten.
+func (stamp FileStamp) HasChanged(filePath string) (bool, error) {
+	stat, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if stat.Size() == stamp.Size && stat.ModTime().Equal(stamp.ModTime) {
+		return false, nil
+	}
+	// the file was touched, but its contents may still be the same
+	hash, err := hashFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(hash, stamp.Hash), nil
+}
+
+func hashFile(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// writeFileAtomically writes contents to a temporary file in the same directory as filePath, syncs it,
+// then renames it over filePath, so that filePath always contains either the old or the new contents.
+// Before replacing the file, the existing file is kept as the most recent of the given number of backups.
+// If filePath is a symbolic link, the file it links to is replaced, while the backups are kept next to the link.
+func writeFileAtomically(filePath string, contents []byte, backups int) (err error) {
+	target := filePath
+	if resolved, evalErr := filepath.EvalSymlinks(filePath); evalErr == nil {
+		target = resolved
+	}
+	dir := filepath.Dir(target)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(target)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(0600); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(contents); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = rotateBackups(filePath, target, backups); err != nil {
+		return fmt.Errorf("unable to create backup: %s", err.Error())
+	}
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// rotateBackups shifts the existing backups of filePath by one, dropping the oldest ones, then
+// keeps a copy of target, the file filePath links to (or filePath itself), as the most recent backup.
+// Existing backups are left alone if no backups should be kept.
+func rotateBackups(filePath, target string, backups int) error {
+	if backups < 1 {
+		return nil
+	}
+	existing, err := ListBackups(filePath)
+	if err != nil {
+		return err
+	}
+	for i := len(existing) - 1; i >= 0; i-- {
+		backup := existing[i]
+		if backup.Number >= backups {
+			err = os.Remove(backup.Path)
+		} else {
+			err = os.Rename(backup.Path, BackupPath(filePath, backup.Number+1))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		return nil
+	}
+	log.Printf("Creating backup of %s", filePath)
+	return copyFile(target, BackupPath(filePath, 1))
+}
+
+// copyFile copies src to dest, which is created with 0600 permissions.
+// A hard link is created instead if possible.
+func copyFile(src, dest string) error {
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir makes a best-effort attempt at persisting the directory entries of dir.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
@@ -0,0 +1,151 @@
+package gohash_db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDatabaseKeepsBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-backups")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, ".go-hash")
+	userPass := "very safe password"
+	options := WriteOptions{
+		KDF:     encryption.KDFParams{KDF: encryption.Argon2id, Time: 1, Memory: 64, Threads: 1, SaltLen: 16},
+		Backups: 3,
+	}
+
+	var states []State
+	for i := 0; i < 5; i++ {
+		db := largeDB()
+		backfillEntries(db)
+		db["default"][0].Description = string(rune('a' + i))
+		states = append(states, db)
+		err = WriteDatabaseWithOptions(dbPath, userPass, options, &db)
+		require.NoError(t, err)
+	}
+
+	backups, err := ListBackups(dbPath)
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+
+	for i, backup := range backups {
+		require.Equal(t, i+1, backup.Number)
+		require.Equal(t, BackupPath(dbPath, i+1), backup.Path)
+		backupState, err := ReadDatabase(backup.Path, userPass)
+		require.NoError(t, err)
+		require.Equal(t, states[len(states)-2-i], backupState)
+	}
+
+	current, err := ReadDatabase(dbPath, userPass)
+	require.NoError(t, err)
+	require.Equal(t, states[len(states)-1], current)
+
+	// no temporary files should be left behind
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	stat, err := os.Stat(dbPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	// reducing the number of backups drops the oldest ones
+	options.Backups = 1
+	err = WriteDatabaseWithOptions(dbPath, userPass, options, &current)
+	require.NoError(t, err)
+	backups, err = ListBackups(dbPath)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+}
+
+func TestWriteDatabaseWithoutBackupsKeepsExistingBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-backups")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, ".go-hash")
+	require.NoError(t, ioutil.WriteFile(dbPath, []byte("current"), 0600))
+	require.NoError(t, ioutil.WriteFile(BackupPath(dbPath, 1), []byte("backup 1"), 0600))
+	require.NoError(t, ioutil.WriteFile(BackupPath(dbPath, 2), []byte("backup 2"), 0600))
+
+	require.NoError(t, writeFileAtomically(dbPath, []byte("new"), 0))
+
+	for path, contents := range map[string]string{
+		dbPath: "new", BackupPath(dbPath, 1): "backup 1", BackupPath(dbPath, 2): "backup 2"} {
+		actual, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, contents, string(actual))
+	}
+}
+
+func TestWriteFileThroughSymbolicLink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-symlink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "sync", "go-hash.db")
+	require.NoError(t, os.Mkdir(filepath.Dir(target), 0700))
+	require.NoError(t, ioutil.WriteFile(target, []byte("old"), 0600))
+	link := filepath.Join(dir, ".go-hash")
+	if err = os.Symlink(target, link); err != nil {
+		t.Skipf("unable to create symbolic link: %s", err.Error())
+	}
+
+	require.NoError(t, writeFileAtomically(link, []byte("new"), 1))
+
+	stat, err := os.Lstat(link)
+	require.NoError(t, err)
+	require.Equal(t, os.ModeSymlink, stat.Mode()&os.ModeSymlink)
+	contents, err := ioutil.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(contents))
+
+	// the backup is a copy of the old file, kept next to the link
+	stat, err = os.Lstat(BackupPath(link, 1))
+	require.NoError(t, err)
+	require.True(t, stat.Mode().IsRegular())
+	contents, err = ioutil.ReadFile(BackupPath(link, 1))
+	require.NoError(t, err)
+	require.Equal(t, "old", string(contents))
+}
+
+func TestFileStampDetectsChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-stamp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "file")
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("hello"), 0600))
+	stamp, err := StampFile(filePath)
+	require.NoError(t, err)
+
+	changed, err := stamp.HasChanged(filePath)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// touching the file does not change it
+	later := stamp.ModTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(filePath, later, later))
+	changed, err = stamp.HasChanged(filePath)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("bye"), 0600))
+	changed, err = stamp.HasChanged(filePath)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	require.NoError(t, os.Remove(filePath))
+	changed, err = stamp.HasChanged(filePath)
+	require.NoError(t, err)
+	require.False(t, changed)
+}
@@ -0,0 +1,207 @@
+package gohash_db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultGroup the group entries belong to unless another group is chosen.
+	// The default group is always a top-level group, and it cannot be removed.
+	DefaultGroup = "default"
+
+	// GroupSeparator separates the names of nested groups in the path of a group, e.g. work/aws/prod.
+	GroupSeparator = "/"
+)
+
+// Groups are stored in a State by their full paths, so work/aws/prod is a sub-group of work/aws, which is
+// a sub-group of the top-level group, work. Groups created before groups could be nested become top-level
+// groups when the database is read, with any '/' in their names replaced (see migrateFlatGroups).
+
+// CleanGroupPath returns the canonical form of a group path, without spaces around the names of groups.
+// Returns an error if the path contains an empty group name, or a ':', which separates groups from entries.
+func CleanGroupPath(path string) (string, error) {
+	if strings.Contains(path, ":") {
+		return "", errors.New("group names cannot contain ':'")
+	}
+	names := strings.Split(path, GroupSeparator)
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if len(names[i]) == 0 {
+			return "", fmt.Errorf("invalid group path '%s', group names cannot be empty", path)
+		}
+	}
+	return strings.Join(names, GroupSeparator), nil
+}
+
+// migrateFlatGroups turns the groups of a state written before groups could be nested into top-level groups.
+// Their names could contain anything, so '/' and ':' are replaced with '-', spaces around names are removed,
+// and empty names become 'unnamed'. A number is added to names that would otherwise be taken, e.g. 'a-b (2)'.
+func migrateFlatGroups(data State) {
+	var names []string
+	for group := range data {
+		if group != TrashGroup {
+			names = append(names, group)
+		}
+	}
+	sort.Strings(names)
+	for _, group := range names {
+		name := strings.TrimSpace(strings.NewReplacer(GroupSeparator, "-", ":", "-").Replace(group))
+		if name == "" {
+			name = "unnamed"
+		}
+		if name == group {
+			continue
+		}
+		candidate := name
+		for n := 2; ; n++ {
+			if _, taken := data[candidate]; !taken {
+				break
+			}
+			candidate = fmt.Sprintf("%s (%d)", name, n)
+		}
+		data[candidate] = data[group]
+		delete(data, group)
+	}
+}
+
+// ParentGroup returns the path of the parent of a group, or DefaultGroup for top-level groups.
+func ParentGroup(path string) string {
+	if i := strings.LastIndex(path, GroupSeparator); i >= 0 {
+		return path[:i]
+	}
+	return DefaultGroup
+}
+
+// GroupName returns the name of a group, without the path of its parent group.
+func GroupName(path string) string {
+	return path[strings.LastIndex(path, GroupSeparator)+1:]
+}
+
+// IsInGroup checks whether the group at path is the group at ancestor, or one of its (nested) sub-groups.
+func IsInGroup(path, ancestor string) bool {
+	return path == ancestor || strings.HasPrefix(path, ancestor+GroupSeparator)
+}
+
+// GroupExists checks whether a group exists. A group exists if it was created, or if any of its
+// sub-groups exist.
+func (data State) GroupExists(path string) bool {
+	for group := range data {
+		if IsInGroup(group, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddGroup creates a group, and all of its parent groups, returning whether any group was created.
+func (data State) AddGroup(path string) bool {
+	created := false
+	for ; path != DefaultGroup; path = ParentGroup(path) {
+		if _, exists := data[path]; !exists {
+			data[path] = []LoginInfo{}
+			created = true
+		}
+	}
+	return created
+}
+
+// SubGroups returns the sorted paths of the direct sub-groups of a group. The sub-groups of the
+// DefaultGroup are all top-level groups, including the DefaultGroup itself.
+func (data State) SubGroups(path string) []string {
+	var subGroups []string
+	seen := make(map[string]bool)
+	for group := range data {
+		var subGroup string
+		switch {
+		case group == TrashGroup:
+			continue
+		case path == DefaultGroup:
+			subGroup = strings.SplitN(group, GroupSeparator, 2)[0]
+		case strings.HasPrefix(group, path+GroupSeparator):
+			name := strings.SplitN(group[len(path)+1:], GroupSeparator, 2)[0]
+			subGroup = path + GroupSeparator + name
+		default:
+			continue
+		}
+		if !seen[subGroup] {
+			seen[subGroup] = true
+			subGroups = append(subGroups, subGroup)
+		}
+	}
+	sort.Strings(subGroups)
+	return subGroups
+}
+
+// CountEntries returns the number of entries in a group and in all of its (nested) sub-groups.
+func (data State) CountEntries(path string) int {
+	count := 0
+	for group, entries := range data {
+		if IsInGroup(group, path) {
+			count += len(entries)
+		}
+	}
+	return count
+}
+
+// RemoveGroup removes a group and all of its (nested) sub-groups.
+// The DefaultGroup cannot be removed, so only its entries are removed.
+func (data State) RemoveGroup(path string) {
+	if path == DefaultGroup {
+		data[DefaultGroup] = []LoginInfo{}
+		return
+	}
+	for _, group := range data.removedGroups(path) {
+		delete(data, group)
+	}
+	// keep the parent group even if it only existed because of its sub-groups
+	data.AddGroup(ParentGroup(path))
+}
+
+// removedGroups returns the sorted paths of the groups whose entries are removed by RemoveGroup: the group
+// and all of its (nested) sub-groups, or only the DefaultGroup itself, whose sub-groups are kept.
+func (data State) removedGroups(path string) []string {
+	if path == DefaultGroup {
+		return []string{DefaultGroup}
+	}
+	var groups []string
+	for group := range data {
+		if IsInGroup(group, path) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// RenameGroup moves a group, and all of its (nested) sub-groups, to a new path.
+// The DefaultGroup cannot be renamed, so only its entries are moved.
+func (data State) RenameGroup(path, newPath string) error {
+	if data.GroupExists(newPath) {
+		return errors.New("group already exists")
+	}
+	if path == DefaultGroup {
+		data.AddGroup(newPath)
+		data[newPath] = data[DefaultGroup]
+		data[DefaultGroup] = []LoginInfo{}
+		return nil
+	}
+	if IsInGroup(newPath, path) {
+		return errors.New("a group cannot be moved into itself")
+	}
+	renamed := make(State)
+	for group, entries := range data {
+		if IsInGroup(group, path) {
+			renamed[newPath+group[len(path):]] = entries
+			delete(data, group)
+		}
+	}
+	for group, entries := range renamed {
+		data[group] = entries
+	}
+	data.AddGroup(newPath)
+	data.AddGroup(ParentGroup(path))
+	return nil
+}
@@ -0,0 +1,95 @@
+package gohash_db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func nestedGroupsDB() State {
+	return State{
+		"default":       {{Name: "gmail"}},
+		"work":          {{Name: "jira"}},
+		"work/aws":      {},
+		"work/aws/prod": {{Name: "console"}, {Name: "db"}},
+		"work/gcp":      {{Name: "console"}},
+		"workshop":      {{Name: "tools"}},
+	}
+}
+
+func TestCleanGroupPath(t *testing.T) {
+	path, err := CleanGroupPath(" work / aws/prod ")
+	require.NoError(t, err)
+	require.Equal(t, "work/aws/prod", path)
+
+	for _, invalid := range []string{"", "/work", "work/", "work//aws", "work:aws"} {
+		_, err = CleanGroupPath(invalid)
+		require.Error(t, err, "Expected group path to be invalid: '%s'", invalid)
+	}
+}
+
+func TestGroupPaths(t *testing.T) {
+	require.Equal(t, "work/aws", ParentGroup("work/aws/prod"))
+	require.Equal(t, DefaultGroup, ParentGroup("work"))
+	require.Equal(t, "prod", GroupName("work/aws/prod"))
+	require.Equal(t, "work", GroupName("work"))
+	require.True(t, IsInGroup("work/aws", "work"))
+	require.True(t, IsInGroup("work", "work"))
+	require.False(t, IsInGroup("workshop", "work"))
+}
+
+func TestSubGroups(t *testing.T) {
+	state := nestedGroupsDB()
+	require.Equal(t, []string{"default", "work", "workshop"}, state.SubGroups(DefaultGroup))
+	require.Equal(t, []string{"work/aws", "work/gcp"}, state.SubGroups("work"))
+	require.Equal(t, []string{"work/aws/prod"}, state.SubGroups("work/aws"))
+	require.Empty(t, state.SubGroups("work/aws/prod"))
+
+	require.Equal(t, 4, state.CountEntries("work"))
+	require.Equal(t, 2, state.CountEntries("work/aws"))
+
+	// groups which only exist because of their sub-groups, e.g. flat groups containing a '/'
+	state = State{"a/b/c": {}}
+	require.True(t, state.GroupExists("a"))
+	require.True(t, state.GroupExists("a/b"))
+	require.False(t, state.GroupExists("a/b/c/d"))
+	require.Equal(t, []string{"a/b"}, state.SubGroups("a"))
+}
+
+func TestAddGroup(t *testing.T) {
+	state := State{"default": {}}
+	require.True(t, state.AddGroup("work/aws/prod"))
+	require.False(t, state.AddGroup("work/aws"))
+	require.Equal(t, State{"default": {}, "work": {}, "work/aws": {}, "work/aws/prod": {}}, state)
+}
+
+func TestRemoveGroup(t *testing.T) {
+	state := nestedGroupsDB()
+	state.RemoveGroup("work/aws")
+	require.Equal(t, []string{"work/gcp"}, state.SubGroups("work"))
+	require.Contains(t, state, "workshop")
+
+	state.RemoveGroup(DefaultGroup)
+	require.Equal(t, []LoginInfo{}, state[DefaultGroup])
+
+	// the parent group is kept even if it only existed because of the removed group
+	state = State{"a/b/c": {}}
+	state.RemoveGroup("a/b/c")
+	require.True(t, state.GroupExists("a/b"))
+}
+
+func TestRenameGroup(t *testing.T) {
+	state := nestedGroupsDB()
+	require.NoError(t, state.RenameGroup("work/aws", "amazon"))
+	require.Equal(t, []string{"amazon", "default", "work", "workshop"}, state.SubGroups(DefaultGroup))
+	require.Equal(t, []string{"amazon/prod"}, state.SubGroups("amazon"))
+	require.Len(t, state["amazon/prod"], 2)
+	require.Equal(t, []string{"work/gcp"}, state.SubGroups("work"))
+
+	require.Error(t, state.RenameGroup("amazon", "work"))
+	require.Error(t, state.RenameGroup("amazon", "amazon/prod/x"))
+
+	require.NoError(t, state.RenameGroup(DefaultGroup, "personal"))
+	require.Equal(t, "gmail", state["personal"][0].Name)
+	require.Empty(t, state[DefaultGroup])
+}
@@ -0,0 +1,119 @@
+package gohash_db
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"runtime"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// kdfParamsLength   KDF | T | M | P | SL
+const kdfParamsLength = 1 + 4 + 4 + 1 + 1
+
+// Header is the unencrypted header of a database.
+// From version GH02, the header is authenticated together with every encrypted block of the database.
+type Header struct {
+	// Version of the database format.
+	Version string
+
+	// KDF the parameters used to hash the master password.
+	KDF encryption.KDFParams
+
+	// Salt used to hash the master password.
+	Salt []byte
+}
+
+// ReadHeader reads the header of the database at filePath.
+// The header is not encrypted, so the password is not required, but it is also not authenticated.
+func ReadHeader(filePath string) (*Header, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	contents := make([]byte, 4+kdfParamsLength+255)
+	n, err := file.ReadAt(contents, 0)
+	if n < 4 {
+		if err == nil {
+			err = errors.New(dbError)
+		}
+		return nil, err
+	}
+
+	version := string(contents[:4])
+	switch version {
+	case DBVersion:
+		header, _, err := decodeHeader(contents[:n])
+		return header, err
+	case DBVersionGH00, DBVersionGH01:
+		if n < 4+32 {
+			return nil, errors.New(dbError)
+		}
+		return &Header{Version: version, KDF: legacyKDFParams(version), Salt: contents[4 : 4+32]}, nil
+	default:
+		return nil, errors.New("Unsupported database version")
+	}
+}
+
+// legacyKDFParams returns the KDF parameters implied by the older database versions.
+func legacyKDFParams(version string) encryption.KDFParams {
+	threads := Argon2Threads
+	if version == DBVersionGH00 {
+		threads = uint8(runtime.NumCPU())
+	}
+	return encryption.KDFParams{
+		KDF:     encryption.Argon2i,
+		Time:    encryption.TIME,
+		Memory:  encryption.MEMORY,
+		Threads: threads,
+		SaltLen: 32,
+	}
+}
+
+// bytes encodes the header as V | KDF | T | M | P | SL | S.
+func (header *Header) bytes() []byte {
+	result := make([]byte, 0, 4+kdfParamsLength+len(header.Salt))
+	result = append(result, []byte(header.Version)...)
+	result = append(result, uint8(header.KDF.KDF))
+	result = appendUint32(result, header.KDF.Time)
+	result = appendUint32(result, header.KDF.Memory)
+	result = append(result, header.KDF.Threads, uint8(len(header.Salt)))
+	return append(result, header.Salt...)
+}
+
+// decodeHeader decodes a header encoded by Header.bytes().
+// Returns the header and its length in bytes.
+func decodeHeader(contents []byte) (*Header, int, error) {
+	if len(contents) < 4+kdfParamsLength {
+		return nil, 0, errors.New(dbError)
+	}
+	params := contents[4:]
+	kdf := encryption.KDFParams{
+		KDF:     encryption.KDF(params[0]),
+		Time:    binary.BigEndian.Uint32(params[1:5]),
+		Memory:  binary.BigEndian.Uint32(params[5:9]),
+		Threads: params[9],
+		SaltLen: params[10],
+	}
+	if err := kdf.Validate(); err != nil {
+		return nil, 0, errors.New(dbError + ": " + err.Error())
+	}
+	length := 4 + kdfParamsLength + int(kdf.SaltLen)
+	if len(contents) < length {
+		return nil, 0, errors.New(dbError)
+	}
+	return &Header{
+		Version: string(contents[:4]),
+		KDF:     kdf,
+		Salt:    contents[4+kdfParamsLength : length],
+	}, length, nil
+}
+
+func appendUint32(b []byte, value uint32) []byte {
+	var encoded [4]byte
+	binary.BigEndian.PutUint32(encoded[:], value)
+	return append(b, encoded[:]...)
+}
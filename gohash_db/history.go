@@ -0,0 +1,48 @@
+package gohash_db
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxPasswordHistory the maximum number of previous passwords kept by an entry.
+const MaxPasswordHistory = 10
+
+// PasswordRecord a password previously used by an entry.
+type PasswordRecord struct {
+	Password string
+	// ReplacedAt the time the password was replaced by another one.
+	ReplacedAt time.Time
+}
+
+// SetPassword changes the password of the entry, keeping the current password, if any, as the most recent
+// password in the entry's PasswordHistory.
+//
+// Only the most recent MaxPasswordHistory passwords are kept.
+func (info *LoginInfo) SetPassword(password string, now time.Time) {
+	if password == info.Password {
+		return
+	}
+	if info.Password != "" {
+		record := PasswordRecord{Password: info.Password, ReplacedAt: now}
+		history := append([]PasswordRecord{record}, info.PasswordHistory...)
+		if len(history) > MaxPasswordHistory {
+			history = history[:MaxPasswordHistory]
+		}
+		info.PasswordHistory = history
+	}
+	info.Password = password
+}
+
+// RestorePassword makes the password at the given index of the entry's PasswordHistory the current password.
+// The current password is kept in the history.
+func (info *LoginInfo) RestorePassword(index int, now time.Time) error {
+	if index < 0 || index >= len(info.PasswordHistory) {
+		return errors.New("no such password in the history")
+	}
+	restored := info.PasswordHistory[index].Password
+	info.PasswordHistory = append(info.PasswordHistory[:index:index], info.PasswordHistory[index+1:]...)
+	info.SetPassword(restored, now)
+	info.UpdatedAt = now
+	return nil
+}
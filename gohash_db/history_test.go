@@ -0,0 +1,59 @@
+package gohash_db
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPasswordKeepsHistory(t *testing.T) {
+	entry := LoginInfo{Name: "google"}
+	entry.SetPassword("p0", t1)
+	require.Equal(t, "p0", entry.Password)
+	require.Empty(t, entry.PasswordHistory)
+
+	entry.SetPassword("p1", t2)
+	entry.SetPassword("p1", t3) // not changed
+	entry.SetPassword("p2", t3)
+	require.Equal(t, "p2", entry.Password)
+	require.Equal(t, []PasswordRecord{
+		{Password: "p1", ReplacedAt: t3},
+		{Password: "p0", ReplacedAt: t2},
+	}, entry.PasswordHistory)
+}
+
+func TestPasswordHistoryIsBounded(t *testing.T) {
+	entry := LoginInfo{Name: "google"}
+	for i := 0; i < MaxPasswordHistory+5; i++ {
+		entry.SetPassword("p"+strconv.Itoa(i), t1.Add(time.Duration(i)*time.Hour))
+	}
+	require.Len(t, entry.PasswordHistory, MaxPasswordHistory)
+	require.Equal(t, "p"+strconv.Itoa(MaxPasswordHistory+3), entry.PasswordHistory[0].Password)
+	require.Equal(t, "p4", entry.PasswordHistory[MaxPasswordHistory-1].Password)
+}
+
+func TestRestorePassword(t *testing.T) {
+	entry := LoginInfo{Name: "google"}
+	entry.SetPassword("p0", t1)
+	entry.SetPassword("p1", t1)
+	entry.SetPassword("p2", t2)
+	copied := entry.Copy()
+
+	require.NoError(t, entry.RestorePassword(1, t3))
+	require.Equal(t, "p0", entry.Password)
+	require.Equal(t, t3, entry.UpdatedAt)
+	require.Equal(t, []PasswordRecord{
+		{Password: "p2", ReplacedAt: t3},
+		{Password: "p1", ReplacedAt: t2},
+	}, entry.PasswordHistory)
+
+	// copies are not affected
+	require.Equal(t, "p2", copied.Password)
+	require.Equal(t, "p1", copied.PasswordHistory[0].Password)
+	require.Equal(t, "p0", copied.PasswordHistory[1].Password)
+
+	require.Error(t, entry.RestorePassword(2, t3))
+	require.Error(t, entry.RestorePassword(-1, t3))
+}
@@ -0,0 +1,91 @@
+package gohash_db
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// legacyEntryNamespace the UUID namespace of the IDs given to entries that were written without an ID.
+var legacyEntryNamespace = []byte{
+	0x3b, 0x8e, 0x2a, 0x4f, 0x61, 0x0d, 0x4c, 0x93, 0x9e, 0x52, 0x17, 0xd4, 0xa0, 0x6c, 0xf1, 0x25}
+
+// ErrEntryNotFound is returned when no entry matches a reference.
+var ErrEntryNotFound = errors.New("entry does not exist")
+
+// NewEntryID returns a new random (version 4) UUID to identify an entry.
+func NewEntryID() string {
+	uuid := encryption.GenerateRandomBytes(16)
+	return formatUUID(uuid, 4)
+}
+
+// legacyEntryID returns a name-based (version 5) UUID for an entry that was written without an ID, e.g. by an
+// older version of go-hash, so that the same ID is given to the entry every time the database is read, on any device.
+func legacyEntryID(group, name string) string {
+	hash := sha1.New()
+	hash.Write(legacyEntryNamespace)
+	hash.Write([]byte(group + ":" + name))
+	return formatUUID(hash.Sum(nil)[:16], 5)
+}
+
+func formatUUID(uuid []byte, version byte) string {
+	uuid[6] = (uuid[6] & 0x0f) | version<<4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// backfillEntries gives an ID and a creation time to the entries that don't have them.
+func backfillEntries(state State) {
+	for group, entries := range state {
+		for i := range entries {
+			entry := &entries[i]
+			if entry.ID == "" {
+				entry.ID = legacyEntryID(group, entry.Name)
+			}
+			if entry.CreatedAt.IsZero() {
+				entry.CreatedAt = entry.UpdatedAt
+			}
+		}
+	}
+}
+
+// AmbiguousIDError is returned when more than one entry matches an ID prefix.
+type AmbiguousIDError struct {
+	Prefix string
+	IDs    []string
+}
+
+func (err *AmbiguousIDError) Error() string {
+	return fmt.Sprintf("more than one entry ID starts with '%s': %s", err.Prefix, strings.Join(err.IDs, ", "))
+}
+
+// FindByIDPrefix finds the entry whose ID starts with the given prefix, returning its group and its index
+// within the group.
+//
+// ErrEntryNotFound is returned if no entry matches, or an *AmbiguousIDError if more than one entry does.
+func (state State) FindByIDPrefix(prefix string) (group string, index int, err error) {
+	prefix = strings.ToLower(prefix)
+	var matches []string
+	index = -1
+	for g, entries := range state.withoutTrash() {
+		for i, entry := range entries {
+			if entry.ID != "" && strings.HasPrefix(entry.ID, prefix) {
+				matches = append(matches, entry.ID)
+				group, index = g, i
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", -1, ErrEntryNotFound
+	case 1:
+		return group, index, nil
+	default:
+		sort.Strings(matches)
+		return "", -1, &AmbiguousIDError{Prefix: prefix, IDs: matches}
+	}
+}
@@ -0,0 +1,61 @@
+package gohash_db
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var uuidPattern = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[45][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+
+func TestNewEntryID(t *testing.T) {
+	id := NewEntryID()
+	require.Regexp(t, uuidPattern, id)
+	require.Equal(t, byte('4'), id[14])
+	require.NotEqual(t, id, NewEntryID())
+}
+
+func TestBackfillEntries(t *testing.T) {
+	db := largeDB()
+	backfillEntries(db)
+
+	ids := make(map[string]bool)
+	for group, entries := range db {
+		for _, entry := range entries {
+			require.Regexp(t, uuidPattern, entry.ID)
+			require.Equal(t, legacyEntryID(group, entry.Name), entry.ID, "IDs should be deterministic")
+			require.Equal(t, entry.UpdatedAt, entry.CreatedAt)
+			ids[entry.ID] = true
+		}
+	}
+	require.Len(t, ids, 6, "IDs should be unique")
+
+	// existing IDs are kept
+	db["default"][0].ID = "custom"
+	backfillEntries(db)
+	require.Equal(t, "custom", db["default"][0].ID)
+}
+
+func TestFindByIDPrefix(t *testing.T) {
+	db := State{
+		"default": []LoginInfo{{ID: "ab12", Name: "a"}, {Name: "no-id"}},
+		"Work":    []LoginInfo{{ID: "ab34", Name: "b"}, {ID: "cd56", Name: "c"}},
+	}
+
+	group, index, err := db.FindByIDPrefix("AB1")
+	require.NoError(t, err)
+	require.Equal(t, "default", group)
+	require.Equal(t, 0, index)
+
+	group, index, err = db.FindByIDPrefix("c")
+	require.NoError(t, err)
+	require.Equal(t, "Work", group)
+	require.Equal(t, 1, index)
+
+	_, _, err = db.FindByIDPrefix("ab")
+	require.Equal(t, &AmbiguousIDError{Prefix: "ab", IDs: []string{"ab12", "ab34"}}, err)
+
+	_, _, err = db.FindByIDPrefix("ef")
+	require.Equal(t, ErrEntryNotFound, err)
+}
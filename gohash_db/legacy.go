@@ -0,0 +1,86 @@
+package gohash_db
+
+import (
+	"errors"
+	"log"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// minLegacyDBLength  V | S  | B1 | B2 | B3 | B4 | MAC| E
+const minLegacyDBLength = 4 + 32 + 32 + 32 + 32 + 32 + 64 + 4
+
+// readLegacyDatabase reads a database in one of the formats that preceded GH02.
+//
+// These formats used AES-CFB without authentication for all blocks, and only the plaintext payload
+// was authenticated by an HMAC, so this is only used for migrating old databases.
+func readLegacyDatabase(contents []byte, version string, password []byte) (State, error) {
+	if len(contents) < minLegacyDBLength {
+		return nil, errors.New(dbError)
+	}
+
+	params := legacyKDFParams(version)
+	log.Printf("Reading %s version of database, threads param set to %d", version, params.Threads)
+
+	fileOffset := 4
+
+	// reads the next block of the given length
+	next := func(length int) []byte {
+		block := contents[fileOffset : fileOffset+length]
+		fileOffset += length
+		return block
+	}
+
+	salt := next(32)
+	log.Println("Salt read successfully, calculating P.")
+
+	P, err := encryption.DeriveKey(password, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	defer encryption.Zero(P)
+	log.Printf("Calculated P, reading Bs.")
+
+	var keys [4][]byte
+	for i := range keys {
+		B := next(32)
+		log.Printf("Read B%d: %x", i+1, B)
+		// Decrypt decrypts in place, so copy the block first
+		keys[i], err = encryption.Decrypt(P, append([]byte{}, B...))
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Decrypted B%d", i+1)
+	}
+
+	K := append(keys[0], keys[1]...)
+	L := append(keys[2], keys[3]...)
+	defer encryption.Zero(K)
+	defer encryption.Zero(L)
+
+	log.Printf("Reading HMAC")
+	mac := next(64)
+
+	payload := append([]byte{}, contents[fileOffset:]...)
+
+	if len(payload) > MaxDBLength {
+		return nil, errors.New(dbError)
+	}
+
+	log.Printf("Decrypting payload with len = %d", len(payload))
+	stateBytes, err := encryption.Decrypt(K, payload)
+	if err != nil {
+		return nil, errors.New(dbError)
+	}
+
+	expectedMac := encryption.Hmac(L, append(append([]byte{}, salt...), stateBytes...))
+
+	log.Printf("Verifying HMAC")
+	if ok := encryption.VerifyHmac(expectedMac, mac); !ok {
+		return nil, errors.New("incorrect password or corrupt database")
+	}
+	log.Printf("Database read successfully")
+
+	// decryption and validation completed successfully!
+	return decodeAndLogState(stateBytes)
+}
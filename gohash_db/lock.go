@@ -0,0 +1,183 @@
+package gohash_db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockInfo identifies the process holding the lock of a database.
+type LockInfo struct {
+	PID      int
+	Hostname string
+	Since    time.Time
+}
+
+func (info LockInfo) String() string {
+	return fmt.Sprintf("process %d on host '%s' (since %s)",
+		info.PID, info.Hostname, info.Since.Format("2006-01-02 15:04:05"))
+}
+
+// LockedError is returned by AcquireLock when another process holds the lock of a database.
+type LockedError struct {
+	Holder LockInfo
+}
+
+func (err *LockedError) Error() string {
+	return "the database is in use by " + err.Holder.String()
+}
+
+// FileLock is an advisory lock on a database file, which prevents other go-hash processes from
+// writing to the same database.
+//
+// The lock is held on a separate lock file (see LockPath), which contains the PID and hostname of
+// the process holding the lock. Where supported, the lock file is also locked with flock, so that the
+// lock is released by the OS if the process dies.
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// LockPath returns the path of the lock file of the database at filePath.
+func LockPath(filePath string) string {
+	return filePath + ".lock"
+}
+
+// AcquireLock acquires the lock of the database at filePath, returning a *LockedError if another
+// live process holds it.
+//
+// Locks left behind by processes that no longer exist are considered stale and are taken over.
+// As only processes running on the current host can be checked, locks held by processes on other
+// hosts (e.g. when the database is in a shared folder) are never considered stale.
+func AcquireLock(filePath string) (*FileLock, error) {
+	lockPath := LockPath(filePath)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		lock, err := tryLock(lockPath, file)
+		if lock == nil && err == nil {
+			// the lock file was removed by its previous holder before we got hold of it, try again
+			file.Close()
+			continue
+		}
+		if err != nil {
+			file.Close()
+		}
+		return lock, err
+	}
+}
+
+func tryLock(lockPath string, file *os.File) (*FileLock, error) {
+	locked, err := flockFile(file)
+	if err != nil {
+		return nil, err
+	}
+	holder, hasHolder := readLockInfo(file)
+	if !locked {
+		if hasHolder {
+			return nil, &LockedError{Holder: holder}
+		}
+		return nil, &LockedError{Holder: LockInfo{Hostname: "unknown"}}
+	}
+
+	// make sure the lock file was not removed while we were acquiring the lock
+	fileStat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pathStat, err := os.Stat(lockPath)
+	if err != nil || !os.SameFile(fileStat, pathStat) {
+		return nil, nil
+	}
+
+	self := currentLockInfo()
+	if hasHolder && !isStale(holder, self) {
+		return nil, &LockedError{Holder: holder}
+	}
+	if hasHolder {
+		log.Printf("Taking over stale lock held by %s", holder)
+	}
+
+	if err = writeLockInfo(file, self); err != nil {
+		return nil, err
+	}
+	return &FileLock{path: lockPath, file: file}, nil
+}
+
+// Release releases the lock, removing the lock file.
+func (lock *FileLock) Release() error {
+	if lock.file == nil {
+		return nil
+	}
+	// remove the file before unlocking it, so that no other process can acquire a lock on it
+	// and still have it removed afterwards
+	err := os.Remove(lock.path)
+	_ = lock.file.Truncate(0)
+	lock.file.Close()
+	lock.file = nil
+	return err
+}
+
+// isStale checks whether the lock held by holder can be taken over by the process identified by self,
+// which already holds the flock of the lock file, if supported.
+func isStale(holder, self LockInfo) bool {
+	if holder.Hostname != self.Hostname {
+		return false
+	}
+	// a live process on this host would still hold the flock
+	return flockSupported || holder.PID == self.PID || !processExists(holder.PID)
+}
+
+func currentLockInfo() LockInfo {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return LockInfo{PID: os.Getpid(), Hostname: hostname, Since: time.Now()}
+}
+
+// lock file format: PID, hostname and time the lock was acquired (Unix seconds), one per line.
+
+func readLockInfo(file *os.File) (LockInfo, bool) {
+	var info LockInfo
+	if _, err := file.Seek(0, 0); err != nil {
+		return info, false
+	}
+	contents, err := ioutil.ReadAll(file)
+	if err != nil {
+		return info, false
+	}
+	lines := strings.Split(string(contents), "\n")
+	if len(lines) < 3 {
+		return info, false
+	}
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return info, false
+	}
+	since, err := strconv.ParseInt(lines[2], 10, 64)
+	if err != nil {
+		return info, false
+	}
+	info.PID = pid
+	info.Hostname = lines[1]
+	info.Since = time.Unix(since, 0)
+	return info, true
+}
+
+func writeLockInfo(file *os.File, info LockInfo) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	contents := fmt.Sprintf("%d\n%s\n%d\n", info.PID, info.Hostname, info.Since.Unix())
+	if _, err := file.WriteAt([]byte(contents), 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !openbsd && !netbsd
+// +build !linux,!darwin,!freebsd,!openbsd,!netbsd
+
+package gohash_db
+
+import "os"
+
+const flockSupported = false
+
+// flockFile is a no-op on this platform, so locks rely only on the contents of the lock file.
+func flockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+// processExists cannot be checked on this platform, so a process is always assumed to exist.
+func processExists(pid int) bool {
+	return true
+}
@@ -0,0 +1,72 @@
+package gohash_db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, ".go-hash")
+
+	lock, err := AcquireLock(dbPath)
+	require.NoError(t, err)
+
+	if flockSupported {
+		_, err = AcquireLock(dbPath)
+		require.IsType(t, &LockedError{}, err)
+		holder := err.(*LockedError).Holder
+		require.Equal(t, os.Getpid(), holder.PID)
+		require.Equal(t, currentLockInfo().Hostname, holder.Hostname)
+	}
+
+	require.NoError(t, lock.Release())
+	_, err = os.Stat(LockPath(dbPath))
+	require.True(t, os.IsNotExist(err), "lock file was not removed")
+
+	lock, err = AcquireLock(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestStaleLockIsTakenOver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, ".go-hash")
+
+	// a lock left behind by a process on this host which no longer exists
+	self := currentLockInfo()
+	writeLockFile(t, dbPath, LockInfo{PID: 1 << 22, Hostname: self.Hostname, Since: time.Now()})
+
+	lock, err := AcquireLock(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestLockHeldOnOtherHostIsRespected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, ".go-hash")
+
+	other := LockInfo{PID: 1234, Hostname: "some-other-host", Since: time.Unix(1500000000, 0)}
+	writeLockFile(t, dbPath, other)
+
+	_, err = AcquireLock(dbPath)
+	require.Equal(t, &LockedError{Holder: other}, err)
+}
+
+func writeLockFile(t *testing.T, dbPath string, info LockInfo) {
+	file, err := os.OpenFile(LockPath(dbPath), os.O_RDWR|os.O_CREATE, 0600)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, writeLockInfo(file, info))
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd
+// +build linux darwin freebsd openbsd netbsd
+
+package gohash_db
+
+import (
+	"os"
+	"syscall"
+)
+
+const flockSupported = true
+
+// flockFile attempts to acquire an exclusive flock on the file without blocking.
+// Returns false if another process holds it.
+func flockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,224 @@
+package gohash_db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+)
+
+// Conflict an entry that was changed differently by both sides of a merge.
+//
+// Local or Remote is nil if the entry was removed on that side.
+type Conflict struct {
+	Group  string
+	Name   string
+	Local  *LoginInfo
+	Remote *LoginInfo
+}
+
+// Newest returns the version of the entry that was updated most recently.
+// A removed entry is only considered newer than an entry that has never been updated.
+func (conflict *Conflict) Newest() *LoginInfo {
+	switch {
+	case conflict.Local == nil:
+		if conflict.Remote.UpdatedAt.IsZero() {
+			return nil
+		}
+		return conflict.Remote
+	case conflict.Remote == nil:
+		if conflict.Local.UpdatedAt.IsZero() {
+			return nil
+		}
+		return conflict.Local
+	case conflict.Remote.UpdatedAt.After(conflict.Local.UpdatedAt):
+		return conflict.Remote
+	default:
+		return conflict.Local
+	}
+}
+
+// ConflictResolver chooses the version of an entry to keep when a Conflict is found,
+// returning nil to remove the entry.
+type ConflictResolver func(conflict Conflict) *LoginInfo
+
+// MergeResult the result of merging two states.
+type MergeResult struct {
+	State State
+	// RemoteChanges the number of entries changed only on the remote side.
+	RemoteChanges int
+	// Conflicts the number of entries that were changed on both sides.
+	Conflicts int
+}
+
+// Merge performs a three-way merge of two versions of a state that were both derived from base.
+//
+// The local state is typically the state of the running session, and remote the state that was saved
+// to the database file by some other process. Entries are identified by their IDs (or by group and name,
+// for entries without an ID), so renamed entries and entries moved to other groups are merged correctly.
+// Entries that were changed on one side only are taken from that side. Entries that were changed on both
+// sides, in different ways, are given to the resolver, which decides which version to keep.
+func Merge(base, local, remote State, resolver ConflictResolver) MergeResult {
+	result := MergeResult{State: make(State, len(local))}
+	baseIndex, localIndex, remoteIndex := indexEntries(base), indexEntries(local), indexEntries(remote)
+
+	for _, key := range mergeEntryKeys(local, remote) {
+		b, l, r := baseIndex[key], localIndex[key], remoteIndex[key]
+
+		var chosen located
+		switch {
+		case l.same(r):
+			chosen = l
+		case b.same(l):
+			chosen = r
+			result.RemoteChanges++
+		case b.same(r):
+			chosen = l
+		default:
+			conflict := Conflict{Group: l.group, Name: l.name(), Local: l.entry, Remote: r.entry}
+			if l.entry == nil {
+				conflict.Group, conflict.Name = r.group, r.name()
+			}
+			resolved := resolver(conflict)
+			switch {
+			case resolved == nil:
+			case resolved == l.entry:
+				chosen = l
+			case resolved == r.entry:
+				chosen = r
+			default:
+				chosen = located{group: conflict.Group, entry: resolved}
+			}
+			result.Conflicts++
+		}
+		if chosen.entry != nil {
+			result.State[chosen.group] = append(result.State[chosen.group], *chosen.entry)
+		}
+	}
+
+	// groups without entries are kept unless they were removed on either side
+	for _, group := range mergeGroupNames(base, local, remote) {
+		_, inBase := base[group]
+		_, inLocal := local[group]
+		_, inRemote := remote[group]
+		if _, hasEntries := result.State[group]; !hasEntries && mergeExists(inBase, inLocal, inRemote) {
+			result.State[group] = []LoginInfo{}
+		}
+	}
+
+	return result
+}
+
+// located an entry, which may be nil, and the group it's in.
+type located struct {
+	group string
+	entry *LoginInfo
+}
+
+func (loc located) name() string {
+	if loc.entry == nil {
+		return ""
+	}
+	return loc.entry.Name
+}
+
+func (loc located) same(other located) bool {
+	return loc.group == other.group && sameEntry(loc.entry, other.entry)
+}
+
+// entryKey returns the key identifying an entry in a merge.
+func entryKey(group string, entry *LoginInfo) string {
+	if entry.ID != "" {
+		return entry.ID
+	}
+	return group + ":" + entry.Name
+}
+
+func indexEntries(state State) map[string]located {
+	index := make(map[string]located)
+	for group, entries := range state {
+		for i := range entries {
+			index[entryKey(group, &entries[i])] = located{group: group, entry: &entries[i]}
+		}
+	}
+	return index
+}
+
+// mergeEntryKeys returns the keys of the local entries, in order, followed by the keys of new remote entries.
+// Entries removed from both sides are not included.
+func mergeEntryKeys(local, remote State) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, state := range []State{local, remote} {
+		groups := make([]string, 0, len(state))
+		for group := range state {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			entries := state[group]
+			for i := range entries {
+				key := entryKey(group, &entries[i])
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// mergeGroupNames returns the sorted names of the groups in any of the states.
+func mergeGroupNames(base, local, remote State) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, state := range []State{base, local, remote} {
+		for group := range state {
+			if !seen[group] {
+				seen[group] = true
+				names = append(names, group)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mergeExists(inBase, inLocal, inRemote bool) bool {
+	if inLocal == inRemote || inLocal == inBase {
+		return inRemote
+	}
+	return inLocal
+}
+
+// sameEntry checks whether two entries, which may be nil, have the same contents.
+func sameEntry(a, b *LoginInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	// compare the serialized entries, so that timestamps compare equal regardless of location and
+	// monotonic clock readings
+	return bytes.Equal(entryBytes(*a), entryBytes(*b))
+}
+
+func entryBytes(entry LoginInfo) []byte {
+	entry.UpdatedAt = entry.UpdatedAt.UTC()
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(&entry); err != nil {
+		panic(err)
+	}
+	return buffer.Bytes()
+}
+
+// Copy returns a copy of the state which can be modified without affecting this state.
+func (state State) Copy() State {
+	result := make(State, len(state))
+	for group, entries := range state {
+		copies := make([]LoginInfo, len(entries))
+		for i, entry := range entries {
+			copies[i] = entry.Copy()
+		}
+		result[group] = copies
+	}
+	return result
+}
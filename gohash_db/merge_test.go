@@ -0,0 +1,188 @@
+package gohash_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	t1 = time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	t2 = time.Date(2019, 1, 2, 10, 0, 0, 0, time.UTC)
+	t3 = time.Date(2019, 1, 3, 10, 0, 0, 0, time.UTC)
+)
+
+func mergeBaseDB() State {
+	return State{
+		"default": []LoginInfo{
+			{Name: "google", Password: "p1", UpdatedAt: t1},
+			{Name: "github", Password: "p2", UpdatedAt: t1},
+		},
+		"Work": []LoginInfo{
+			{Name: "VPN", Password: "p3", UpdatedAt: t1},
+		},
+	}
+}
+
+func failOnConflict(t *testing.T) ConflictResolver {
+	return func(conflict Conflict) *LoginInfo {
+		t.Fatalf("Unexpected conflict: %+v", conflict)
+		return nil
+	}
+}
+
+func TestMergeNonConflictingChanges(t *testing.T) {
+	base := mergeBaseDB()
+
+	local := base.Copy()
+	local["default"][0].Password = "new local"
+	local["default"][0].UpdatedAt = t2
+	local["Personal"] = []LoginInfo{{Name: "facebook", Password: "p4", UpdatedAt: t2}}
+
+	remote := base.Copy()
+	remote["default"] = remote["default"][:1] // removed github
+	remote["Work"][0].Password = "new remote" // changed VPN
+	remote["Work"] = append(remote["Work"], LoginInfo{Name: "jira", UpdatedAt: t3})
+
+	result := Merge(base, local, remote, failOnConflict(t))
+
+	require.Equal(t, 3, result.RemoteChanges)
+	require.Equal(t, 0, result.Conflicts)
+	require.Equal(t, State{
+		"default": []LoginInfo{
+			{Name: "google", Password: "new local", UpdatedAt: t2},
+		},
+		"Work": []LoginInfo{
+			{Name: "VPN", Password: "new remote", UpdatedAt: t1},
+			{Name: "jira", UpdatedAt: t3},
+		},
+		"Personal": []LoginInfo{
+			{Name: "facebook", Password: "p4", UpdatedAt: t2},
+		},
+	}, result.State)
+
+	// the inputs must not be modified
+	require.Equal(t, mergeBaseDB(), base)
+}
+
+func TestMergeIdenticalChanges(t *testing.T) {
+	base := mergeBaseDB()
+	local := base.Copy()
+	local["default"][1].Password = "same"
+	remote := base.Copy()
+	remote["default"][1].Password = "same"
+
+	result := Merge(base, local, remote, failOnConflict(t))
+	require.Equal(t, 0, result.RemoteChanges)
+	require.Equal(t, local, result.State)
+}
+
+func TestMergeConflicts(t *testing.T) {
+	base := mergeBaseDB()
+
+	local := base.Copy()
+	local["default"][0].Password = "local"
+	local["default"][0].UpdatedAt = t2
+	local["Work"] = []LoginInfo{}
+
+	remote := base.Copy()
+	remote["default"][0].Password = "remote"
+	remote["default"][0].UpdatedAt = t3
+	remote["Work"][0].UpdatedAt = t2
+
+	var conflicts []Conflict
+	result := Merge(base, local, remote, func(conflict Conflict) *LoginInfo {
+		conflicts = append(conflicts, conflict)
+		return conflict.Newest()
+	})
+
+	require.Equal(t, 2, result.Conflicts)
+	require.Len(t, conflicts, 2)
+	require.Equal(t, "google", conflicts[0].Name)
+	require.Equal(t, "VPN", conflicts[1].Name)
+	require.Nil(t, conflicts[1].Local)
+
+	require.Equal(t, State{
+		"default": []LoginInfo{
+			{Name: "google", Password: "remote", UpdatedAt: t3},
+			{Name: "github", Password: "p2", UpdatedAt: t1},
+		},
+		"Work": []LoginInfo{
+			{Name: "VPN", Password: "p3", UpdatedAt: t2},
+		},
+	}, result.State)
+}
+
+func TestMergeRemovedGroups(t *testing.T) {
+	base := mergeBaseDB()
+	local := base.Copy()
+	delete(local, "Work")
+	remote := base.Copy()
+	remote["Empty"] = []LoginInfo{}
+
+	result := Merge(base, local, remote, failOnConflict(t))
+	require.Equal(t, State{
+		"default": base["default"],
+		"Empty":   []LoginInfo{},
+	}, result.State)
+}
+
+func TestMergeTimestampsInOtherLocations(t *testing.T) {
+	base := mergeBaseDB()
+	local := base.Copy()
+	remote := base.Copy()
+	for _, entries := range remote {
+		for i := range entries {
+			entries[i].UpdatedAt = entries[i].UpdatedAt.In(time.FixedZone("X", 0))
+		}
+	}
+	result := Merge(base, local, remote, failOnConflict(t))
+	require.Equal(t, 0, result.RemoteChanges)
+}
+
+func TestMergeRenamedAndMovedEntries(t *testing.T) {
+	base := State{
+		"default": []LoginInfo{
+			{ID: "1", Name: "google", Password: "p1", UpdatedAt: t1},
+			{ID: "2", Name: "github", Password: "p2", UpdatedAt: t1},
+		},
+		"Work": []LoginInfo{},
+	}
+
+	// local renames google and moves github to Work
+	local := base.Copy()
+	local["default"] = []LoginInfo{{ID: "1", Name: "gmail", Password: "p1", UpdatedAt: t2}}
+	local["Work"] = []LoginInfo{{ID: "2", Name: "github", Password: "p2", UpdatedAt: t2}}
+
+	// remote changes the passwords of both entries
+	remote := base.Copy()
+	remote["default"][0].Password = "new p1"
+	remote["default"][1].Password = "new p2"
+	remote["default"][1].UpdatedAt = t3
+
+	var conflicts []Conflict
+	result := Merge(base, local, remote, func(conflict Conflict) *LoginInfo {
+		conflicts = append(conflicts, conflict)
+		return conflict.Newest()
+	})
+
+	// both entries were changed on both sides, but they are still recognized as the same entries
+	require.Len(t, conflicts, 2)
+	require.Equal(t, "github", conflicts[0].Name)
+	require.Equal(t, "Work", conflicts[0].Group)
+	require.Equal(t, "gmail", conflicts[1].Name)
+	require.Equal(t, "google", conflicts[1].Remote.Name)
+
+	require.Equal(t, State{
+		"default": []LoginInfo{
+			{ID: "2", Name: "github", Password: "new p2", UpdatedAt: t3},
+			{ID: "1", Name: "gmail", Password: "p1", UpdatedAt: t2},
+		},
+		"Work": []LoginInfo{},
+	}, result.State)
+
+	// without remote changes, the renamed and moved entries are not duplicated
+	result = Merge(base, local, base.Copy(), failOnConflict(t))
+	require.Equal(t, local, result.State)
+}
@@ -0,0 +1,96 @@
+package gohash_db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEntryExists is returned when an entry can't be added to a group because the group already has
+// an entry with the same name.
+var ErrEntryExists = errors.New("an entry with the same name already exists in the group")
+
+// ErrGroupNotFound is returned when a group that should exist does not.
+var ErrGroupNotFound = errors.New("group does not exist")
+
+// FindEntry returns the index of the entry with the given name in a group, if any.
+func (data State) FindEntry(group, name string) (int, bool) {
+	for i := range data[group] {
+		if data[group][i].Name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// RemoveEntry removes an entry from the state, returning the removed entry.
+func (data State) RemoveEntry(ref EntryRef) LoginInfo {
+	entries := data[ref.Group]
+	removed := entries[ref.Index]
+	data[ref.Group] = append(entries[:ref.Index:ref.Index], entries[ref.Index+1:]...)
+	return removed
+}
+
+// MoveEntry moves an entry to the target group, renaming it to newName, unless newName is empty.
+// The entry keeps its ID and timestamps. Returns the new location of the entry.
+//
+// ErrEntryExists is returned if the target group already has another entry with the same name,
+// and ErrGroupNotFound if the target group does not exist.
+func (data State) MoveEntry(ref EntryRef, targetGroup, newName string) (EntryRef, error) {
+	entry := data[ref.Group][ref.Index]
+	if newName == "" {
+		newName = entry.Name
+	}
+	if !data.GroupExists(targetGroup) {
+		return ref, ErrGroupNotFound
+	}
+	if i, exists := data.FindEntry(targetGroup, newName); exists {
+		if targetGroup == ref.Group && i == ref.Index {
+			return ref, nil // nothing to do
+		}
+		return ref, ErrEntryExists
+	}
+	data.RemoveEntry(ref)
+	entry.Name = newName
+	data[targetGroup] = append(data[targetGroup], entry)
+	return EntryRef{Group: targetGroup, Index: len(data[targetGroup]) - 1}, nil
+}
+
+// CopyEntry adds a copy of an entry to the target group, named newName, unless newName is empty.
+// The copy is a new entry, with a new ID, created at the given time. Returns the location of the copy.
+//
+// ErrEntryExists is returned if the target group already has an entry with the same name
+// (see UniqueEntryName), and ErrGroupNotFound if the target group does not exist.
+func (data State) CopyEntry(ref EntryRef, targetGroup, newName string, now time.Time) (EntryRef, error) {
+	entry := data[ref.Group][ref.Index].Copy()
+	if newName == "" {
+		newName = entry.Name
+	}
+	if !data.GroupExists(targetGroup) {
+		return ref, ErrGroupNotFound
+	}
+	if _, exists := data.FindEntry(targetGroup, newName); exists {
+		return ref, ErrEntryExists
+	}
+	entry.ID = NewEntryID()
+	entry.Name = newName
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	data[targetGroup] = append(data[targetGroup], entry)
+	return EntryRef{Group: targetGroup, Index: len(data[targetGroup]) - 1}, nil
+}
+
+// UniqueEntryName returns a name based on the given name which is not used by any entry of the group,
+// suitable for a copy of an entry, e.g. 'gmail (copy)' or 'gmail (copy 2)'.
+func (data State) UniqueEntryName(group, name string) string {
+	if _, exists := data.FindEntry(group, name); !exists {
+		return name
+	}
+	candidate := name + " (copy)"
+	for n := 2; ; n++ {
+		if _, exists := data.FindEntry(group, candidate); !exists {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (copy %d)", name, n)
+	}
+}
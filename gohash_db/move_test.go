@@ -0,0 +1,81 @@
+package gohash_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func moveDB() State {
+	updatedAt := time.Unix(1500000000, 0)
+	return State{
+		"default": {
+			{ID: "1", Name: "gmail", Password: "secret", UpdatedAt: updatedAt,
+				PasswordHistory: []PasswordRecord{{Password: "old"}}},
+			{ID: "2", Name: "github"},
+		},
+		"work": {
+			{ID: "3", Name: "github"},
+		},
+	}
+}
+
+func TestMoveEntry(t *testing.T) {
+	state := moveDB()
+	ref, err := state.MoveEntry(EntryRef{Group: "default", Index: 0}, "work", "")
+	require.NoError(t, err)
+	require.Equal(t, EntryRef{Group: "work", Index: 1}, ref)
+	moved := state["work"][1]
+	require.Equal(t, "1", moved.ID)
+	require.Equal(t, "gmail", moved.Name)
+	require.Equal(t, time.Unix(1500000000, 0), moved.UpdatedAt)
+	require.Len(t, state["default"], 1)
+
+	// name collisions
+	_, err = state.MoveEntry(EntryRef{Group: "default", Index: 0}, "work", "")
+	require.Equal(t, ErrEntryExists, err)
+	ref, err = state.MoveEntry(EntryRef{Group: "default", Index: 0}, "work", "github-personal")
+	require.NoError(t, err)
+	require.Equal(t, "github-personal", state["work"][ref.Index].Name)
+	require.Empty(t, state["default"])
+
+	// moving an entry to where it already is does nothing
+	ref, err = state.MoveEntry(EntryRef{Group: "work", Index: 0}, "work", "github")
+	require.NoError(t, err)
+	require.Equal(t, EntryRef{Group: "work", Index: 0}, ref)
+
+	_, err = state.MoveEntry(EntryRef{Group: "work", Index: 0}, "other", "")
+	require.Equal(t, ErrGroupNotFound, err)
+}
+
+func TestCopyEntry(t *testing.T) {
+	state := moveDB()
+	now := time.Now()
+	_, err := state.CopyEntry(EntryRef{Group: "default", Index: 1}, "work", "", now)
+	require.Equal(t, ErrEntryExists, err)
+
+	name := state.UniqueEntryName("default", "gmail")
+	require.Equal(t, "gmail (copy)", name)
+	ref, err := state.CopyEntry(EntryRef{Group: "default", Index: 0}, "default", name, now)
+	require.NoError(t, err)
+	require.Equal(t, "gmail (copy 2)", state.UniqueEntryName("default", "gmail"))
+
+	original, copied := &state["default"][0], &state["default"][ref.Index]
+	require.NotEqual(t, original.ID, copied.ID)
+	require.Equal(t, "secret", copied.Password)
+	require.Equal(t, now, copied.CreatedAt)
+	require.Equal(t, now, copied.UpdatedAt)
+
+	// the copy is independent from the original entry
+	copied.PasswordHistory[0].Password = "changed"
+	require.Equal(t, "old", original.PasswordHistory[0].Password)
+}
+
+func TestRemoveEntry(t *testing.T) {
+	state := moveDB()
+	removed := state.RemoveEntry(EntryRef{Group: "default", Index: 0})
+	require.Equal(t, "gmail", removed.Name)
+	require.Len(t, state["default"], 1)
+	require.Equal(t, "github", state["default"][0].Name)
+}
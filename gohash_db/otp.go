@@ -0,0 +1,196 @@
+package gohash_db
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// OTPType the type of one-time passwords generated for an entry.
+type OTPType uint8
+
+const (
+	// TOTP time-based one-time passwords (RFC 6238).
+	TOTP OTPType = iota
+	// HOTP counter-based one-time passwords (RFC 4226).
+	HOTP
+)
+
+func (otpType OTPType) String() string {
+	switch otpType {
+	case TOTP:
+		return "totp"
+	case HOTP:
+		return "hotp"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(otpType))
+}
+
+const (
+	// DefaultOTPDigits the number of digits of one-time passwords, unless specified otherwise.
+	DefaultOTPDigits = 6
+
+	// DefaultOTPPeriod the period of time-based one-time passwords, in seconds, unless specified otherwise.
+	DefaultOTPPeriod = 30
+)
+
+// OTP the information required to generate one-time passwords (used for two-factor authentication)
+// for an entry, as found in otpauth URIs.
+type OTP struct {
+	Type OTPType
+	// Secret the base32-encoded shared secret, without padding.
+	Secret    string
+	Algorithm encryption.OTPAlgorithm
+	Digits    int
+	// Period the period of time-based one-time passwords, in seconds.
+	Period int
+	// Counter the counter of the next counter-based one-time password.
+	Counter uint64
+	Issuer  string
+	Account string
+}
+
+var otpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ParseOTP parses either an otpauth URI (as encoded in the QR codes shown by websites when setting up
+// two-factor authentication), or a base32-encoded secret, in which case the default TOTP parameters are used.
+func ParseOTP(text string) (*OTP, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(strings.ToLower(text), "otpauth://") {
+		return parseOTPAuthURI(text)
+	}
+	otp := &OTP{Type: TOTP, Secret: text, Digits: DefaultOTPDigits, Period: DefaultOTPPeriod}
+	return otp, otp.normalize()
+}
+
+func parseOTPAuthURI(uri string) (*OTP, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	otp := &OTP{Digits: DefaultOTPDigits, Period: DefaultOTPPeriod}
+	switch strings.ToLower(parsed.Host) {
+	case "totp":
+		otp.Type = TOTP
+	case "hotp":
+		otp.Type = HOTP
+	default:
+		return nil, fmt.Errorf("unknown OTP type: %s", parsed.Host)
+	}
+
+	// the label is either "account" or "issuer:account"
+	label := strings.TrimPrefix(parsed.Path, "/")
+	if i := strings.Index(label, ":"); i >= 0 {
+		otp.Issuer, otp.Account = strings.TrimSpace(label[:i]), strings.TrimSpace(label[i+1:])
+	} else {
+		otp.Account = strings.TrimSpace(label)
+	}
+
+	query := parsed.Query()
+	otp.Secret = query.Get("secret")
+	if issuer := query.Get("issuer"); issuer != "" {
+		otp.Issuer = issuer
+	}
+	if alg := query.Get("algorithm"); alg != "" {
+		if otp.Algorithm, err = encryption.ParseOTPAlgorithm(alg); err != nil {
+			return nil, err
+		}
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if otp.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("invalid number of OTP digits: %s", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		if otp.Period, err = strconv.Atoi(period); err != nil {
+			return nil, fmt.Errorf("invalid OTP period: %s", period)
+		}
+	}
+	if counter := query.Get("counter"); counter != "" {
+		if otp.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid OTP counter: %s", counter)
+		}
+	} else if otp.Type == HOTP {
+		return nil, errors.New("HOTP URI does not contain a counter")
+	}
+	return otp, otp.normalize()
+}
+
+// normalize the secret, so that it is accepted regardless of case, spaces or padding, then validate the OTP.
+func (otp *OTP) normalize() error {
+	secret := strings.ToUpper(strings.Join(strings.Fields(otp.Secret), ""))
+	otp.Secret = strings.TrimRight(secret, "=")
+	if len(otp.Secret) == 0 {
+		return errors.New("missing OTP secret")
+	}
+	if _, err := otp.secret(); err != nil {
+		return errors.New("invalid OTP secret, it must be base32-encoded")
+	}
+	if otp.Digits < encryption.MinOTPDigits || otp.Digits > encryption.MaxOTPDigits {
+		return fmt.Errorf("invalid number of OTP digits: %d", otp.Digits)
+	}
+	if otp.Period < 1 {
+		return fmt.Errorf("invalid OTP period: %d", otp.Period)
+	}
+	return nil
+}
+
+func (otp *OTP) secret() ([]byte, error) {
+	return otpBase32.DecodeString(otp.Secret)
+}
+
+// Code generates the current one-time password.
+//
+// For TOTP, also returns the time remaining until the code expires. For HOTP, the counter is incremented,
+// so the OTP must be saved afterwards, and the remaining time is always 0.
+func (otp *OTP) Code(now time.Time) (string, time.Duration, error) {
+	secret, err := otp.secret()
+	if err != nil {
+		return "", 0, err
+	}
+	defer encryption.Zero(secret)
+	if otp.Type == HOTP {
+		code, err := encryption.HOTP(secret, otp.Counter, otp.Digits, otp.Algorithm)
+		if err == nil {
+			otp.Counter++
+		}
+		return code, 0, err
+	}
+	return encryption.TOTP(secret, now, time.Duration(otp.Period)*time.Second, otp.Digits, otp.Algorithm)
+}
+
+// URI returns the otpauth URI of the OTP, which can be used to set up the OTP in another application.
+func (otp *OTP) URI() string {
+	label := otp.Account
+	if otp.Issuer != "" {
+		label = otp.Issuer + ":" + otp.Account
+	}
+	query := url.Values{}
+	query.Set("secret", otp.Secret)
+	if otp.Issuer != "" {
+		query.Set("issuer", otp.Issuer)
+	}
+	query.Set("algorithm", otp.Algorithm.String())
+	query.Set("digits", strconv.Itoa(otp.Digits))
+	if otp.Type == HOTP {
+		query.Set("counter", strconv.FormatUint(otp.Counter, 10))
+	} else {
+		query.Set("period", strconv.Itoa(otp.Period))
+	}
+	uri := url.URL{Scheme: "otpauth", Host: otp.Type.String(), Path: "/" + label, RawQuery: query.Encode()}
+	return uri.String()
+}
+
+// String describes the OTP without revealing its secret.
+func (otp *OTP) String() string {
+	if otp.Type == HOTP {
+		return fmt.Sprintf("HOTP (%s, %d digits, counter %d)", otp.Algorithm, otp.Digits, otp.Counter)
+	}
+	return fmt.Sprintf("TOTP (%s, %d digits, %ds period)", otp.Algorithm, otp.Digits, otp.Period)
+}
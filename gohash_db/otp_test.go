@@ -0,0 +1,80 @@
+package gohash_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+// base32 encoding of the secret used in the RFC 4226 and RFC 6238 test vectors
+const rfcOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestParseOTPAuthURI(t *testing.T) {
+	otp, err := ParseOTP("otpauth://totp/Example:alice@example.com?secret=" + rfcOTPSecret +
+		"&issuer=Example&algorithm=SHA256&digits=8&period=60")
+	require.NoError(t, err)
+	require.Equal(t, &OTP{
+		Type:      TOTP,
+		Secret:    rfcOTPSecret,
+		Algorithm: encryption.OTPSHA256,
+		Digits:    8,
+		Period:    60,
+		Issuer:    "Example",
+		Account:   "alice@example.com",
+	}, otp)
+
+	parsed, err := ParseOTP(otp.URI())
+	require.NoError(t, err)
+	require.Equal(t, otp, parsed)
+}
+
+func TestParseOTPSecret(t *testing.T) {
+	otp, err := ParseOTP(" gezd gnbv gy3t qojq gezd gnbv gy3t qojq ")
+	require.NoError(t, err)
+	require.Equal(t, &OTP{Type: TOTP, Secret: rfcOTPSecret, Digits: DefaultOTPDigits, Period: DefaultOTPPeriod}, otp)
+
+	code, remaining, err := otp.Code(time.Unix(59, 0))
+	require.NoError(t, err)
+	require.Equal(t, "287082", code)
+	require.Equal(t, 1*time.Second, remaining)
+	require.NotContains(t, otp.String(), rfcOTPSecret)
+}
+
+func TestHOTPCounterIsIncremented(t *testing.T) {
+	otp, err := ParseOTP("otpauth://hotp/alice?secret=" + rfcOTPSecret + "&counter=1")
+	require.NoError(t, err)
+	for _, expected := range []string{"287082", "359152", "969429"} {
+		code, _, err := otp.Code(time.Now())
+		require.NoError(t, err)
+		require.Equal(t, expected, code)
+	}
+	require.Equal(t, uint64(4), otp.Counter)
+}
+
+func TestInvalidOTPs(t *testing.T) {
+	invalid := []string{
+		"",
+		"not base32!",
+		"otpauth://other/alice?secret=" + rfcOTPSecret,
+		"otpauth://hotp/alice?secret=" + rfcOTPSecret,
+		"otpauth://totp/alice?secret=" + rfcOTPSecret + "&digits=4",
+		"otpauth://totp/alice?secret=" + rfcOTPSecret + "&period=0",
+		"otpauth://totp/alice?secret=" + rfcOTPSecret + "&algorithm=MD5",
+		"otpauth://totp/alice",
+	}
+	for _, text := range invalid {
+		_, err := ParseOTP(text)
+		require.Error(t, err, "Expected OTP to be invalid: %s", text)
+	}
+}
+
+func TestOTPIsCopiedWithEntry(t *testing.T) {
+	entry := LoginInfo{Name: "bank"}
+	entry.OTP, _ = ParseOTP(rfcOTPSecret)
+	copied := entry.Copy()
+	entry.OTP.Digits = 8
+	require.Equal(t, DefaultOTPDigits, copied.OTP.Digits)
+	require.Contains(t, copied.String(), "TOTP (SHA1, 6 digits, 30s period)")
+}
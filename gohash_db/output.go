@@ -0,0 +1,301 @@
+package gohash_db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// OutputSchemaVersion the version of the schema of the JSON and YAML documents describing groups and entries.
+// Fields may be added to the documents without changing the version, which is only incremented when fields
+// are removed or their meaning changes.
+const OutputSchemaVersion = 1
+
+// OutputFormat the format used to print groups and entries.
+type OutputFormat uint8
+
+const (
+	// TableFormat human-readable text. This is the default format.
+	TableFormat OutputFormat = iota
+
+	// JSONFormat JSON documents, following the schema given by OutputSchemaVersion.
+	JSONFormat
+
+	// YAMLFormat YAML documents, with the same structure as the JSON documents.
+	YAMLFormat
+)
+
+func (format OutputFormat) String() string {
+	switch format {
+	case TableFormat:
+		return "table"
+	case JSONFormat:
+		return "json"
+	case YAMLFormat:
+		return "yaml"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(format))
+	}
+}
+
+// ParseOutputFormat parses the name of an OutputFormat: table, json or yaml.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch strings.ToLower(name) {
+	case "table":
+		return TableFormat, nil
+	case "json":
+		return JSONFormat, nil
+	case "yaml", "yml":
+		return YAMLFormat, nil
+	default:
+		return TableFormat, fmt.Errorf("unknown output format '%s' (use table, json or yaml)", name)
+	}
+}
+
+// Listing the document describing a group, its sub-groups and its entries, or the entries found by a query.
+type Listing struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// Group the path of the listed group, if any.
+	Group string `json:"group,omitempty"`
+	// Tag the tag of the listed entries, if the entries are listed by tag.
+	Tag     string        `json:"tag,omitempty"`
+	Groups  []GroupOutput `json:"groups"`
+	Entries []EntryOutput `json:"entries"`
+}
+
+// EntryDocument the document describing a single entry.
+type EntryDocument struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Entry         EntryOutput `json:"entry"`
+}
+
+// GroupOutput describes a group in a Listing.
+type GroupOutput struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	// Entries the number of entries directly in the group.
+	Entries int `json:"entries"`
+	// SubGroups the number of direct sub-groups of the group.
+	SubGroups int `json:"subGroups"`
+}
+
+// EntryOutput describes an entry. Secrets (passwords, the values of secret fields and the contents of notes)
+// are only included if requested.
+type EntryOutput struct {
+	ID          string             `json:"id"`
+	Kind        string             `json:"kind"`
+	Group       string             `json:"group"`
+	Name        string             `json:"name"`
+	Username    string             `json:"username,omitempty"`
+	URL         string             `json:"url,omitempty"`
+	Password    string             `json:"password,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Note        string             `json:"note,omitempty"`
+	Fields      []FieldOutput      `json:"fields,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	OTP         *OTPOutput         `json:"otp,omitempty"`
+	Attachments []AttachmentOutput `json:"attachments,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+// FieldOutput describes a custom field of an entry. The value of secret fields is only included if requested.
+type FieldOutput struct {
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Secret bool   `json:"secret"`
+}
+
+// OTPOutput describes how the one-time passwords of an entry are generated, without the secret.
+type OTPOutput struct {
+	Type      string `json:"type"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period,omitempty"`
+	Counter   uint64 `json:"counter,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
+	Account   string `json:"account,omitempty"`
+}
+
+// AttachmentOutput describes a file attached to an entry, without its contents.
+type AttachmentOutput struct {
+	Name    string    `json:"name"`
+	Size    int       `json:"size"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// NewListing creates the Listing of a group, with its direct sub-groups and its entries.
+func (data State) NewListing(path string, withSecrets bool) Listing {
+	listing := Listing{SchemaVersion: OutputSchemaVersion, Group: path,
+		Groups: []GroupOutput{}, Entries: []EntryOutput{}}
+	for _, subGroup := range data.SubGroups(path) {
+		if subGroup == path {
+			continue // the default group is listed as a top-level group, but it's not its own sub-group
+		}
+		listing.Groups = append(listing.Groups, GroupOutput{Path: subGroup, Name: GroupName(subGroup),
+			Entries: len(data[subGroup]), SubGroups: len(data.SubGroups(subGroup))})
+	}
+	for i := range data[path] {
+		listing.Entries = append(listing.Entries, data[path][i].Output(path, withSecrets))
+	}
+	return listing
+}
+
+// NewEntryDocument creates the EntryDocument describing an entry of the given group.
+func NewEntryDocument(group string, info *LoginInfo, withSecrets bool) EntryDocument {
+	return EntryDocument{SchemaVersion: OutputSchemaVersion, Entry: info.Output(group, withSecrets)}
+}
+
+// Output describes the entry, which belongs to the given group. Secrets are only included if withSecrets is true.
+func (info *LoginInfo) Output(group string, withSecrets bool) EntryOutput {
+	output := EntryOutput{
+		ID:          info.ID,
+		Kind:        info.Kind.String(),
+		Group:       group,
+		Name:        info.Name,
+		Username:    info.Username,
+		URL:         info.URL,
+		Description: info.Description,
+		Tags:        info.Tags,
+		CreatedAt:   info.CreatedAt,
+		UpdatedAt:   info.UpdatedAt,
+	}
+	if withSecrets {
+		output.Password = info.Password
+		output.Note = info.Note
+	}
+	for _, field := range info.Fields {
+		fieldOutput := FieldOutput{Name: field.Name, Secret: field.Secret}
+		if withSecrets || !field.Secret {
+			fieldOutput.Value = field.Value
+		}
+		output.Fields = append(output.Fields, fieldOutput)
+	}
+	if otp := info.OTP; otp != nil {
+		output.OTP = &OTPOutput{Type: otp.Type.String(), Algorithm: otp.Algorithm.String(), Digits: otp.Digits,
+			Issuer: otp.Issuer, Account: otp.Account}
+		if otp.Type == HOTP {
+			output.OTP.Counter = otp.Counter
+		} else {
+			output.OTP.Period = otp.Period
+		}
+	}
+	for _, attachment := range info.Attachments {
+		output.Attachments = append(output.Attachments, AttachmentOutput{Name: attachment.Name,
+			Size: len(attachment.Data), AddedAt: attachment.AddedAt})
+	}
+	return output
+}
+
+// WriteOutput writes a document (a Listing or an EntryDocument) in the JSON or YAML format.
+func WriteOutput(w io.Writer, format OutputFormat, document interface{}) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	switch format {
+	case JSONFormat:
+		_, err := buffer.WriteTo(w)
+		return err
+	case YAMLFormat:
+		yaml, err := jsonToYAML(buffer.Bytes())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(yaml)
+		return err
+	default:
+		return fmt.Errorf("cannot write documents in the %s format", format)
+	}
+}
+
+// jsonToYAML converts a JSON document to YAML, keeping the order of the fields of objects.
+// Strings are written as double-quoted YAML scalars, which accept the same escape sequences as JSON strings.
+func jsonToYAML(document []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var buffer bytes.Buffer
+	if err := writeYAMLValue(&buffer, decoder, 0, ""); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeYAMLValue writes the next value of the decoder. Scalars and empty collections are written after the
+// prefix, on the current line, while objects and arrays start on a new line, indented by the given number of spaces.
+func writeYAMLValue(buffer *bytes.Buffer, decoder *json.Decoder, indent int, prefix string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	padding := strings.Repeat(" ", indent)
+	switch token {
+	case json.Delim('{'):
+		if !decoder.More() {
+			buffer.WriteString(prefix + "{}\n")
+			_, err = decoder.Token()
+			return err
+		}
+		if prefix != "" {
+			buffer.WriteString(strings.TrimSuffix(prefix, " ") + "\n")
+		}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if err = writeYAMLValue(buffer, decoder, indent+2, padding+key.(string)+": "); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		if !decoder.More() {
+			buffer.WriteString(prefix + "[]\n")
+			_, err = decoder.Token()
+			return err
+		}
+		if prefix != "" {
+			buffer.WriteString(strings.TrimSuffix(prefix, " ") + "\n")
+		}
+		for decoder.More() {
+			// items are written as '- ' followed by the value, whose lines are indented to align with it
+			var item bytes.Buffer
+			if err = writeYAMLValue(&item, decoder, 2, ""); err != nil {
+				return err
+			}
+			lines := strings.SplitAfter(strings.TrimSuffix(item.String(), "\n"), "\n")
+			buffer.WriteString(padding + "- " + strings.TrimPrefix(lines[0], "  "))
+			for _, line := range lines[1:] {
+				buffer.WriteString(padding + line)
+			}
+			buffer.WriteString("\n")
+		}
+	default:
+		buffer.WriteString(prefix + yamlScalar(token) + "\n")
+		return nil
+	}
+	// consume the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+func yamlScalar(token json.Token) string {
+	switch value := token.(type) {
+	case nil:
+		return "null"
+	case string:
+		var quoted bytes.Buffer
+		encoder := json.NewEncoder(&quoted)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(value)
+		return strings.TrimSuffix(quoted.String(), "\n")
+	default:
+		return fmt.Sprint(value)
+	}
+}
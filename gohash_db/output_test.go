@@ -0,0 +1,109 @@
+package gohash_db
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+func outputDB() State {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	return State{
+		"default": {},
+		"work": {
+			{ID: "1", Name: "aws", Username: "admin", Password: "secret", URL: "https://aws.amazon.com",
+				CreatedAt: createdAt, UpdatedAt: createdAt, Tags: []string{"infra"},
+				Fields: []CustomField{{Name: "pin", Value: "1234", Secret: true}, {Name: "account", Value: "42"}},
+				OTP:    &OTP{Type: TOTP, Secret: "JBSWY3DPEHPK3PXP", Algorithm: encryption.OTPSHA1, Digits: 6, Period: 30}},
+			{ID: "2", Kind: NoteKind, Name: "codes", Note: "a\nb\n", CreatedAt: createdAt, UpdatedAt: createdAt},
+		},
+		"work/aws": {},
+	}
+}
+
+func TestEntryOutputExcludesSecrets(t *testing.T) {
+	state := outputDB()
+	output := state["work"][0].Output("work", false)
+	require.Equal(t, "", output.Password)
+	require.Equal(t, []FieldOutput{{Name: "pin", Secret: true}, {Name: "account", Value: "42"}}, output.Fields)
+	require.Equal(t, &OTPOutput{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30}, output.OTP)
+	require.Equal(t, "", state["work"][1].Output("work", false).Note)
+
+	output = state["work"][0].Output("work", true)
+	require.Equal(t, "secret", output.Password)
+	require.Equal(t, "1234", output.Fields[0].Value)
+	require.Equal(t, "a\nb\n", state["work"][1].Output("work", true).Note)
+}
+
+func TestWriteJSONListing(t *testing.T) {
+	state := outputDB()
+	var buffer bytes.Buffer
+	require.NoError(t, WriteOutput(&buffer, JSONFormat, state.NewListing("work", false)))
+
+	var listing map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &listing))
+	require.Equal(t, float64(OutputSchemaVersion), listing["schemaVersion"])
+	require.Equal(t, "work", listing["group"])
+	require.Equal(t, []interface{}{map[string]interface{}{
+		"path": "work/aws", "name": "aws", "entries": float64(0), "subGroups": float64(0)}}, listing["groups"])
+	entries := listing["entries"].([]interface{})
+	require.Len(t, entries, 2)
+	require.NotContains(t, entries[0], "password")
+	require.Equal(t, "2020-01-02T03:04:05Z", entries[0].(map[string]interface{})["createdAt"])
+
+	// the default group is not its own sub-group, and empty lists are not omitted
+	listing = nil
+	buffer.Reset()
+	require.NoError(t, WriteOutput(&buffer, JSONFormat, state.NewListing(DefaultGroup, false)))
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &listing))
+	require.Len(t, listing["groups"], 1)
+	require.Equal(t, []interface{}{}, listing["entries"])
+}
+
+func TestWriteYAML(t *testing.T) {
+	state := outputDB()
+	var buffer bytes.Buffer
+	require.NoError(t, WriteOutput(&buffer, YAMLFormat, NewEntryDocument("work", &state["work"][0], true)))
+	require.Equal(t, `schemaVersion: 1
+entry:
+  id: "1"
+  kind: "login"
+  group: "work"
+  name: "aws"
+  username: "admin"
+  url: "https://aws.amazon.com"
+  password: "secret"
+  fields:
+    - name: "pin"
+      value: "1234"
+      secret: true
+    - name: "account"
+      value: "42"
+      secret: false
+  tags:
+    - "infra"
+  otp:
+    type: "totp"
+    algorithm: "SHA1"
+    digits: 6
+    period: 30
+  createdAt: "2020-01-02T03:04:05Z"
+  updatedAt: "2020-01-02T03:04:05Z"
+`, buffer.String())
+
+	buffer.Reset()
+	require.NoError(t, WriteOutput(&buffer, YAMLFormat, state.NewListing("work/aws", false)))
+	require.Equal(t, "schemaVersion: 1\ngroup: \"work/aws\"\ngroups: []\nentries: []\n", buffer.String())
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	format, err := ParseOutputFormat("JSON")
+	require.NoError(t, err)
+	require.Equal(t, JSONFormat, format)
+	_, err = ParseOutputFormat("xml")
+	require.Error(t, err)
+}
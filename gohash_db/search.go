@@ -0,0 +1,137 @@
+package gohash_db
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SearchResult an entry found by Search, and how well it matches the query.
+type SearchResult struct {
+	EntryRef
+	// Score higher scores indicate better matches.
+	Score int
+}
+
+// scores of a single word matching a piece of text, from the best to the worst kind of match.
+const (
+	exactMatchScore       = 100
+	prefixMatchScore      = 80
+	substringMatchScore   = 60
+	subsequenceMatchScore = 30
+)
+
+// searchField a piece of information of an entry that is searched, and its weight in the score.
+type searchField struct {
+	text   string
+	weight int
+}
+
+// Search finds the entries, in all groups, matching the query, best matches first.
+//
+// The query is split into words, and every word must match at least one of the name, URL host, username,
+// description or tags of an entry, ignoring case. Words match exactly, as a prefix, as a substring or,
+// at worst, fuzzily, i.e. if all of their characters appear in the same order in the matched text.
+// Matches in the name of entries score the highest, followed by tags and URL hosts.
+func (data State) Search(query string) []SearchResult {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+	var results []SearchResult
+	for group, entries := range data.withoutTrash() {
+		for i := range entries {
+			if score := searchScore(words, &entries[i]); score > 0 {
+				results = append(results, SearchResult{EntryRef: EntryRef{Group: group, Index: i}, Score: score})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return data[a.Group][a.Index].Name < data[b.Group][b.Index].Name
+	})
+	return results
+}
+
+func searchScore(words []string, info *LoginInfo) int {
+	fields := []searchField{
+		{info.Name, 3},
+		{urlHost(info.URL), 2},
+		{info.Username, 1},
+		{info.Description, 1},
+	}
+	for _, tag := range info.Tags {
+		fields = append(fields, searchField{tag, 2})
+	}
+
+	total := 0
+	for _, word := range words {
+		best := 0
+		for _, field := range fields {
+			if score := field.weight * matchScore(word, strings.ToLower(field.text)); score > best {
+				best = score
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total
+}
+
+// matchScore returns how well a word matches some text, or 0 if it doesn't match at all.
+func matchScore(word, text string) int {
+	switch {
+	case len(text) == 0:
+		return 0
+	case text == word:
+		return exactMatchScore
+	case strings.HasPrefix(text, word):
+		return prefixMatchScore
+	case strings.Contains(text, word):
+		return substringMatchScore
+	}
+	// fuzzy match: every character of the word appears in the text, in order, and
+	// the fewer characters are skipped, the better
+	skipped, i := 0, 0
+	runes := []rune(word)
+	for _, c := range text {
+		if i == len(runes) {
+			break
+		}
+		if c == runes[i] {
+			i++
+		} else if i > 0 {
+			skipped++
+		}
+	}
+	if i < len(runes) {
+		return 0
+	}
+	if score := subsequenceMatchScore - skipped; score > 1 {
+		return score
+	}
+	return 1
+}
+
+// urlHost returns the host of a URL, which may not include the scheme.
+func urlHost(rawURL string) string {
+	if len(rawURL) == 0 {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
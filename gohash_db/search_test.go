@@ -0,0 +1,59 @@
+package gohash_db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func searchDB() State {
+	return State{
+		"default": {
+			{Name: "gmail", URL: "https://mail.google.com", Username: "joe"},
+			{Name: "github", URL: "github.com/login", Username: "joe", Tags: []string{"dev"}},
+		},
+		"work/aws": {
+			{Name: "console", URL: "https://aws.amazon.com", Username: "admin", Tags: []string{"infra"}},
+			{Name: "jira", Description: "issue tracker for the github project"},
+		},
+	}
+}
+
+func foundNames(state State, results []SearchResult) []string {
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = state[result.Group][result.Index].Name
+	}
+	return names
+}
+
+func TestSearchRanksResults(t *testing.T) {
+	state := searchDB()
+
+	// exact name match first, then matches in the description
+	require.Equal(t, []string{"github", "jira"}, foundNames(state, state.Search("GitHub")))
+
+	// prefix matches rank higher than fuzzy matches (github.com)
+	require.Equal(t, []string{"gmail", "github"}, foundNames(state, state.Search("gm")))
+
+	// URL hosts and tags are searched
+	require.Equal(t, []string{"console"}, foundNames(state, state.Search("amazon")))
+	require.Equal(t, []string{"console"}, foundNames(state, state.Search("infra")))
+	require.Equal(t, []string{"gmail"}, foundNames(state, state.Search("google")))
+
+	// every word must match
+	require.Equal(t, []string{"github"}, foundNames(state, state.Search("joe dev")))
+	require.Empty(t, state.Search("joe admin"))
+	require.Empty(t, state.Search("   "))
+}
+
+func TestFuzzySearch(t *testing.T) {
+	state := searchDB()
+	results := state.Search("cnsl")
+	require.Equal(t, []string{"console"}, foundNames(state, results))
+	require.Equal(t, EntryRef{Group: "work/aws", Index: 0}, results[0].EntryRef)
+	require.Empty(t, state.Search("xyz"))
+
+	require.True(t, matchScore("gh", "github") > matchScore("gh", "gmail-helper"))
+	require.Equal(t, 0, matchScore("hg", "github"))
+}
@@ -0,0 +1,99 @@
+package gohash_db
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// ErrKeyMismatch is returned when a database cannot be read with a SessionKey because the database was
+// written with a different master password, salt or KDF parameters.
+var ErrKeyMismatch = errors.New("the database was not written with this session key")
+
+// SessionKey holds the key material derived from the master password of a database, so that the
+// database can be read and saved again without keeping the master password, or hashing it again.
+//
+// The derived keys are kept in an encryption.LockedBuffer, which is zeroed out by Destroy.
+// The same salt and encrypted key (B) are used every time the database is written with a SessionKey.
+type SessionKey struct {
+	header     []byte // V | KDF | T | M | P | SL | S
+	kdf        encryption.KDFParams
+	wrappedKey []byte                   // B
+	secrets    *encryption.LockedBuffer // P | K
+}
+
+// NewSessionKey derives a new SessionKey from the password, with a new random salt and key.
+func NewSessionKey(password []byte, params encryption.KDFParams) (*SessionKey, error) {
+	salt := encryption.GenerateRandomBytes(uint32(params.SaltLen))
+	header := (&Header{Version: DBVersion, KDF: params, Salt: salt}).bytes()
+
+	P, err := encryption.DeriveKey(password, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	defer encryption.Zero(P)
+
+	K := encryption.GenerateRandomBytes(32)
+	defer encryption.Zero(K)
+
+	B, err := encryption.EncryptAEAD(P, K, header)
+	if err != nil {
+		return nil, err
+	}
+	return newSessionKey(header, params, B, P, K), nil
+}
+
+func newSessionKey(header []byte, params encryption.KDFParams, B, P, K []byte) *SessionKey {
+	secrets := encryption.NewLockedBuffer(len(P) + len(K))
+	copy(secrets.Bytes(), P)
+	copy(secrets.Bytes()[len(P):], K)
+	return &SessionKey{
+		header:     append([]byte{}, header...),
+		kdf:        params,
+		wrappedKey: append([]byte{}, B...),
+		secrets:    secrets,
+	}
+}
+
+// KDF returns the parameters used to derive this key from the master password.
+func (key *SessionKey) KDF() encryption.KDFParams {
+	return key.kdf
+}
+
+// VerifyPassword checks whether the given password is the master password this key was derived from.
+func (key *SessionKey) VerifyPassword(password []byte) bool {
+	header, _, err := decodeHeader(key.header)
+	if err != nil {
+		return false
+	}
+	P, err := encryption.DeriveKey(password, header.Salt, key.kdf)
+	if err != nil {
+		return false
+	}
+	defer encryption.Zero(P)
+	return subtle.ConstantTimeCompare(P, key.passwordHash()) == 1
+}
+
+// Destroy zeroes out the key material. The key cannot be used after being destroyed.
+func (key *SessionKey) Destroy() {
+	key.secrets.Destroy()
+}
+
+// passwordHash returns P, the hash of the master password.
+func (key *SessionKey) passwordHash() []byte {
+	secrets := key.secrets.Bytes()
+	if secrets == nil {
+		panic("SessionKey used after being destroyed")
+	}
+	return secrets[:encryption.KEYLEN]
+}
+
+// dataKey returns K, the key used to encrypt the state.
+func (key *SessionKey) dataKey() []byte {
+	secrets := key.secrets.Bytes()
+	if secrets == nil {
+		panic("SessionKey used after being destroyed")
+	}
+	return secrets[encryption.KEYLEN:]
+}
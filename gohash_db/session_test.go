@@ -0,0 +1,109 @@
+package gohash_db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+var fastKDFParams = encryption.KDFParams{KDF: encryption.Argon2id, Time: 1, Memory: 64, Threads: 1, SaltLen: 16}
+
+func TestWriteDatabaseWithKey(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/SessionKeyDB"
+	userPass := []byte("very safe password")
+	db := largeDB()
+	backfillEntries(db)
+
+	key, err := NewSessionKey(userPass, fastKDFParams)
+	require.NoError(t, err)
+	defer key.Destroy()
+	require.Equal(t, fastKDFParams, key.KDF())
+
+	err = WriteDatabaseWithKey(tmpDbPath, key, 0, &db)
+	require.NoError(t, err)
+	first, err := ioutil.ReadFile(tmpDbPath)
+	require.NoError(t, err)
+
+	persistedState, reopenedKey, err := OpenDatabase(tmpDbPath, userPass)
+	require.NoError(t, err)
+	defer reopenedKey.Destroy()
+	require.Equal(t, db, persistedState)
+
+	// writing again with the re-opened key keeps the same header and wrapped key
+	db["Work"] = append(db["Work"], LoginInfo{ID: NewEntryID(), Name: "new"})
+	err = WriteDatabaseWithKey(tmpDbPath, reopenedKey, 0, &db)
+	require.NoError(t, err)
+	second, err := ioutil.ReadFile(tmpDbPath)
+	require.NoError(t, err)
+	prefixLength := 4 + kdfParamsLength + int(fastKDFParams.SaltLen) + wrappedKeyLength
+	require.Equal(t, first[:prefixLength], second[:prefixLength])
+	require.NotEqual(t, first[prefixLength:], second[prefixLength:])
+
+	persistedState, err = ReadDatabase(tmpDbPath, string(userPass))
+	require.NoError(t, err)
+	require.Equal(t, db, persistedState)
+
+	persistedState, err = ReadDatabaseWithKey(tmpDbPath, key)
+	require.NoError(t, err)
+	require.Equal(t, db, persistedState)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	key, err := NewSessionKey([]byte("very safe password"), fastKDFParams)
+	require.NoError(t, err)
+	defer key.Destroy()
+
+	require.True(t, key.VerifyPassword([]byte("very safe password")))
+	require.False(t, key.VerifyPassword([]byte("wrong password")))
+}
+
+func TestReadDatabaseWithOtherKey(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/OtherSessionKeyDB"
+	db := simpleDB()
+	key, err := NewSessionKey([]byte("very safe password"), fastKDFParams)
+	require.NoError(t, err)
+	defer key.Destroy()
+	err = WriteDatabaseWithKey(tmpDbPath, key, 0, &db)
+	require.NoError(t, err)
+
+	otherKey, err := NewSessionKey([]byte("very safe password"), fastKDFParams)
+	require.NoError(t, err)
+	defer otherKey.Destroy()
+
+	_, err = ReadDatabaseWithKey(tmpDbPath, otherKey)
+	require.Equal(t, ErrKeyMismatch, err)
+}
+
+func TestOpenLegacyDatabaseUpgradesKey(t *testing.T) {
+	tmpDbPath := os.TempDir() + "/LegacySessionKeyDB"
+	userPass := "very safe password"
+	db := largeDB()
+	err := writeLegacyDatabase(tmpDbPath, DBVersionGH01, userPass, &db)
+	require.NoError(t, err)
+
+	persistedState, key, err := OpenDatabase(tmpDbPath, []byte(userPass))
+	require.NoError(t, err)
+	defer key.Destroy()
+	backfillEntries(db)
+	require.Equal(t, db, persistedState)
+	require.Equal(t, encryption.DefaultKDFParams(), key.KDF())
+
+	err = WriteDatabaseWithKey(tmpDbPath, key, 0, &persistedState)
+	require.NoError(t, err)
+	header, err := ReadHeader(tmpDbPath)
+	require.NoError(t, err)
+	require.Equal(t, DBVersion, header.Version)
+}
+
+func TestDestroyedSessionKeyCannotBeUsed(t *testing.T) {
+	key, err := NewSessionKey([]byte("very safe password"), fastKDFParams)
+	require.NoError(t, err)
+	key.Destroy()
+	db := simpleDB()
+	require.Panics(t, func() {
+		_ = WriteDatabaseWithKey(os.TempDir()+"/DestroyedKeyDB", key, 0, &db)
+	})
+}
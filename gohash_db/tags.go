@@ -0,0 +1,106 @@
+package gohash_db
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// EntryRef refers to an entry of a State by its group and its index within the group.
+type EntryRef struct {
+	Group string
+	Index int
+}
+
+// NormalizeTag returns the canonical form of a tag, so that tags are case-insensitive.
+// Tags cannot be empty or contain whitespace or commas.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if len(tag) == 0 {
+		return "", errors.New("tags cannot be empty")
+	}
+	if strings.ContainsAny(tag, " \t\r\n,") {
+		return "", errors.New("tags cannot contain spaces or commas")
+	}
+	return tag, nil
+}
+
+// HasTag checks whether the entry has the given tag.
+func (info *LoginInfo) HasTag(tag string) bool {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return false
+	}
+	i := sort.SearchStrings(info.Tags, tag)
+	return i < len(info.Tags) && info.Tags[i] == tag
+}
+
+// AddTag adds a tag to the entry, keeping the tags sorted.
+// Returns whether the tag was added, or an error if the tag is invalid (see NormalizeTag).
+func (info *LoginInfo) AddTag(tag string) (bool, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return false, err
+	}
+	i := sort.SearchStrings(info.Tags, tag)
+	if i < len(info.Tags) && info.Tags[i] == tag {
+		return false, nil
+	}
+	tags := make([]string, 0, len(info.Tags)+1)
+	tags = append(tags, info.Tags[:i]...)
+	tags = append(tags, tag)
+	info.Tags = append(tags, info.Tags[i:]...)
+	return true, nil
+}
+
+// RemoveTag removes a tag from the entry, returning whether the entry had the tag.
+func (info *LoginInfo) RemoveTag(tag string) bool {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return false
+	}
+	i := sort.SearchStrings(info.Tags, tag)
+	if i < len(info.Tags) && info.Tags[i] == tag {
+		info.Tags = append(info.Tags[:i:i], info.Tags[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// Tags returns all tags used by entries of the state, sorted, and how many entries have each tag.
+func (data State) Tags() ([]string, map[string]int) {
+	counts := make(map[string]int)
+	for _, entries := range data.withoutTrash() {
+		for i := range entries {
+			for _, tag := range entries[i].Tags {
+				counts[tag]++
+			}
+		}
+	}
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, counts
+}
+
+// FindByTag returns the entries of all groups that have the given tag, sorted by group, then by name.
+func (data State) FindByTag(tag string) []EntryRef {
+	var refs []EntryRef
+	for group, entries := range data.withoutTrash() {
+		for i := range entries {
+			if entries[i].HasTag(tag) {
+				refs = append(refs, EntryRef{Group: group, Index: i})
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return data[a.Group][a.Index].Name < data[b.Group][b.Index].Name
+	})
+	return refs
+}
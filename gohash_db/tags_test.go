@@ -0,0 +1,50 @@
+package gohash_db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	entry := LoginInfo{Name: "aws"}
+	for _, tag := range []string{"work", "Infra", "cloud", "WORK"} {
+		_, err := entry.AddTag(tag)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"cloud", "infra", "work"}, entry.Tags)
+	require.True(t, entry.HasTag("INFRA"))
+	require.False(t, entry.HasTag("home"))
+	require.Contains(t, entry.String(), "cloud, infra, work")
+
+	copied := entry.Copy()
+	require.True(t, entry.RemoveTag("cloud"))
+	require.False(t, entry.RemoveTag("cloud"))
+	require.Equal(t, []string{"infra", "work"}, entry.Tags)
+	require.Equal(t, []string{"cloud", "infra", "work"}, copied.Tags)
+
+	for _, tag := range []string{"", " ", "two words", "a,b"} {
+		_, err := entry.AddTag(tag)
+		require.Error(t, err, "Expected tag to be invalid: '%s'", tag)
+	}
+}
+
+func TestFindByTag(t *testing.T) {
+	state := State{
+		"work": {
+			{Name: "jira", Tags: []string{"work"}},
+			{Name: "aws", Tags: []string{"infra", "work"}},
+		},
+		"default": {
+			{Name: "gmail"},
+			{Name: "vps", Tags: []string{"infra"}},
+		},
+	}
+	require.Equal(t, []EntryRef{{Group: "default", Index: 1}, {Group: "work", Index: 1}}, state.FindByTag("infra"))
+	require.Equal(t, []EntryRef{{Group: "work", Index: 1}, {Group: "work", Index: 0}}, state.FindByTag("Work"))
+	require.Empty(t, state.FindByTag("home"))
+
+	tags, counts := state.Tags()
+	require.Equal(t, []string{"infra", "work"}, tags)
+	require.Equal(t, map[string]int{"infra": 2, "work": 2}, counts)
+}
@@ -0,0 +1,193 @@
+package gohash_db
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// TrashGroup the group holding the entries in the trash. Group names cannot contain ':', so it cannot clash
+	// with the groups created by users. It's not listed as a group, and its entries are not found by searches.
+	TrashGroup = ":trash"
+
+	// DefaultTrashRetention how long entries are kept in the trash before being purged.
+	DefaultTrashRetention = 30 * 24 * time.Hour
+)
+
+// Deletion records when and from where an entry in the trash was deleted.
+type Deletion struct {
+	DeletedAt time.Time
+	// Group the group the entry was in when it was deleted.
+	Group string
+	// RemovedGroup the path of the removed group, if the entry was deleted together with a whole group.
+	RemovedGroup string
+}
+
+// TrashItem an item in the trash: a single entry, or all entries of a group deleted together.
+type TrashItem struct {
+	// Group the group the entry was deleted from, or the removed group if the item is a whole group.
+	Group string
+	// Entry the name of the deleted entry, or empty if the item is a whole group.
+	Entry     string
+	DeletedAt time.Time
+	// Entries the number of entries in the item.
+	Entries int
+	ids     []string
+}
+
+// IsGroup checks whether the item is a whole group, rather than a single entry.
+func (item *TrashItem) IsGroup() bool {
+	return item.Entry == ""
+}
+
+// DeleteEntry moves an entry to the trash, from where it can be restored until it's purged.
+// Use RemoveEntry to remove an entry permanently.
+func (data State) DeleteEntry(ref EntryRef, now time.Time) {
+	entry := data.RemoveEntry(ref)
+	entry.Deletion = &Deletion{DeletedAt: now, Group: ref.Group}
+	data[TrashGroup] = append(data[TrashGroup], entry)
+}
+
+// DeleteGroup moves a group, and all of its (nested) sub-groups, to the trash, returning the number of entries
+// moved to the trash. Empty groups are simply removed. Use RemoveGroup to remove a group permanently.
+// As with RemoveGroup, only the entries of the DefaultGroup are deleted, not its sub-groups.
+func (data State) DeleteGroup(path string, now time.Time) int {
+	count := 0
+	for _, group := range data.removedGroups(path) {
+		for _, entry := range data[group] {
+			entry.Deletion = &Deletion{DeletedAt: now, Group: group, RemovedGroup: path}
+			data[TrashGroup] = append(data[TrashGroup], entry)
+			count++
+		}
+	}
+	data.RemoveGroup(path)
+	return count
+}
+
+// Trash returns the items in the trash, most recently deleted first.
+func (data State) Trash() []TrashItem {
+	var items []TrashItem
+	type groupKey struct {
+		deletedAt int64
+		group     string
+	}
+	groupItems := make(map[groupKey]int)
+	for i := range data[TrashGroup] {
+		entry := &data[TrashGroup][i]
+		deletion := entry.deletion()
+		if deletion.RemovedGroup == "" {
+			items = append(items, TrashItem{Group: deletion.Group, Entry: entry.Name,
+				DeletedAt: deletion.DeletedAt, Entries: 1, ids: []string{entry.ID}})
+			continue
+		}
+		// entries deleted together with a group are grouped in a single item
+		key := groupKey{deletedAt: deletion.DeletedAt.UnixNano(), group: deletion.RemovedGroup}
+		if j, exists := groupItems[key]; exists {
+			items[j].Entries++
+			items[j].ids = append(items[j].ids, entry.ID)
+		} else {
+			groupItems[key] = len(items)
+			items = append(items, TrashItem{Group: deletion.RemovedGroup,
+				DeletedAt: deletion.DeletedAt, Entries: 1, ids: []string{entry.ID}})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items
+}
+
+// RestoreTrashItem moves the entries of an item in the trash back to the groups they were deleted from,
+// re-creating the groups if necessary. Entries whose names are now taken are given a unique name
+// (see UniqueEntryName). Returns the new locations of the entries.
+func (data State) RestoreTrashItem(item TrashItem) []EntryRef {
+	var refs []EntryRef
+	for _, entry := range data.takeFromTrash(item) {
+		group := entry.deletion().Group
+		entry.Deletion = nil
+		data.AddGroup(group)
+		entry.Name = data.UniqueEntryName(group, entry.Name)
+		data[group] = append(data[group], entry)
+		refs = append(refs, EntryRef{Group: group, Index: len(data[group]) - 1})
+	}
+	if item.IsGroup() {
+		// the group may have been empty
+		data.AddGroup(item.Group)
+	}
+	return refs
+}
+
+// PurgeTrashItem removes the entries of an item in the trash permanently.
+func (data State) PurgeTrashItem(item TrashItem) {
+	data.takeFromTrash(item)
+}
+
+// PurgeTrash permanently removes the entries that were moved to the trash before the given time,
+// returning the number of entries removed.
+func (data State) PurgeTrash(deletedBefore time.Time) int {
+	var kept []LoginInfo
+	trash := data[TrashGroup]
+	for _, entry := range trash {
+		if !entry.deletion().DeletedAt.Before(deletedBefore) {
+			kept = append(kept, entry)
+		}
+	}
+	data.setTrash(kept)
+	return len(trash) - len(kept)
+}
+
+// EmptyTrash permanently removes all entries in the trash, returning the number of entries removed.
+func (data State) EmptyTrash() int {
+	count := len(data[TrashGroup])
+	data.setTrash(nil)
+	return count
+}
+
+// takeFromTrash removes the entries of an item from the trash, returning them.
+func (data State) takeFromTrash(item TrashItem) []LoginInfo {
+	ids := make(map[string]bool, len(item.ids))
+	for _, id := range item.ids {
+		ids[id] = true
+	}
+	var taken, kept []LoginInfo
+	for _, entry := range data[TrashGroup] {
+		if ids[entry.ID] {
+			taken = append(taken, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	data.setTrash(kept)
+	return taken
+}
+
+func (data State) setTrash(entries []LoginInfo) {
+	if len(entries) == 0 {
+		delete(data, TrashGroup)
+	} else {
+		data[TrashGroup] = entries
+	}
+}
+
+// deletion returns the Deletion of an entry in the trash. Entries without one are treated as if they had just
+// been deleted from the default group, so they are never purged before being seen.
+func (info *LoginInfo) deletion() Deletion {
+	if info.Deletion == nil {
+		return Deletion{DeletedAt: time.Now(), Group: DefaultGroup}
+	}
+	return *info.Deletion
+}
+
+// withoutTrash returns a shallow copy of the state without the TrashGroup.
+func (data State) withoutTrash() State {
+	if _, exists := data[TrashGroup]; !exists {
+		return data
+	}
+	result := make(State, len(data))
+	for group, entries := range data {
+		if group != TrashGroup {
+			result[group] = entries
+		}
+	}
+	return result
+}
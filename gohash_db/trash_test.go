@@ -0,0 +1,109 @@
+package gohash_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAndRestoreEntry(t *testing.T) {
+	state := moveDB()
+	deletedAt := time.Unix(1600000000, 0)
+	state.DeleteEntry(EntryRef{Group: "default", Index: 0}, deletedAt)
+	require.Len(t, state["default"], 1)
+
+	items := state.Trash()
+	require.Equal(t, []TrashItem{{Group: "default", Entry: "gmail", DeletedAt: deletedAt, Entries: 1,
+		ids: []string{"1"}}}, items)
+
+	// the trash is not a group, and its entries can't be found
+	require.Equal(t, []string{"default", "work"}, state.SubGroups(DefaultGroup))
+	require.Empty(t, state.Search("gmail"))
+	_, _, err := state.FindByIDPrefix("1")
+	require.Equal(t, ErrEntryNotFound, err)
+
+	// restored entries keep their ID, but not their deletion
+	refs := state.RestoreTrashItem(items[0])
+	require.Equal(t, []EntryRef{{Group: "default", Index: 1}}, refs)
+	restored := state["default"][1]
+	require.Equal(t, "1", restored.ID)
+	require.Equal(t, "secret", restored.Password)
+	require.Nil(t, restored.Deletion)
+	require.Empty(t, state.Trash())
+	require.NotContains(t, state, TrashGroup)
+}
+
+func TestDeleteAndRestoreGroup(t *testing.T) {
+	state := moveDB()
+	state.AddGroup("work/aws/prod")
+	state["work/aws"] = []LoginInfo{{ID: "4", Name: "console"}}
+	now := time.Now()
+	state.DeleteEntry(EntryRef{Group: "work", Index: 0}, now.Add(-time.Hour))
+	require.Equal(t, 1, state.DeleteGroup("work", now))
+	require.False(t, state.GroupExists("work"))
+
+	items := state.Trash()
+	require.Len(t, items, 2)
+	require.True(t, items[0].IsGroup())
+	require.Equal(t, "work", items[0].Group)
+	require.Equal(t, 1, items[0].Entries)
+	require.Equal(t, "github", items[1].Entry)
+
+	// a new entry takes the name of the deleted one
+	state.AddGroup("work")
+	state["work"] = append(state["work"], LoginInfo{ID: "5", Name: "github"})
+
+	state.RestoreTrashItem(items[0])
+	require.Equal(t, "console", state["work/aws"][0].Name)
+	require.True(t, state.GroupExists("work"))
+	state.RestoreTrashItem(items[1])
+	require.Equal(t, "github (copy)", state["work"][1].Name)
+	require.Empty(t, state.Trash())
+}
+
+func TestDeleteDefaultGroupKeepsItsSubGroups(t *testing.T) {
+	state := moveDB()
+	state["default/personal"] = []LoginInfo{{ID: "4", Name: "bank"}}
+	require.Equal(t, 2, state.DeleteGroup(DefaultGroup, time.Now()))
+	require.Empty(t, state["default"])
+	require.Equal(t, []LoginInfo{{ID: "4", Name: "bank"}}, state["default/personal"])
+
+	items := state.Trash()
+	require.Len(t, items, 1)
+	require.Equal(t, 2, items[0].Entries)
+
+	state.RestoreTrashItem(items[0])
+	require.Len(t, state["default"], 2)
+	require.Len(t, state["default/personal"], 1)
+	require.Empty(t, state.Trash())
+}
+
+func TestPurgeTrash(t *testing.T) {
+	state := moveDB()
+	now := time.Now()
+	state.DeleteEntry(EntryRef{Group: "default", Index: 0}, now.Add(-31*24*time.Hour))
+	state.DeleteEntry(EntryRef{Group: "default", Index: 0}, now)
+	state.DeleteEntry(EntryRef{Group: "work", Index: 0}, now)
+
+	require.Equal(t, 1, state.PurgeTrash(now.Add(-DefaultTrashRetention)))
+	items := state.Trash()
+	require.Len(t, items, 2)
+
+	state.PurgeTrashItem(items[0])
+	require.Len(t, state.Trash(), 1)
+	require.Equal(t, 1, state.EmptyTrash())
+	require.Empty(t, state.Trash())
+	require.NotContains(t, state, TrashGroup)
+}
+
+func TestUndoDescribesTrash(t *testing.T) {
+	state := moveDB()
+	before := state.Copy()
+	state.DeleteEntry(EntryRef{Group: "default", Index: 0}, time.Now())
+	require.Equal(t, "removes 1 entry", describeChanges(before, state, "adds"))
+
+	before = state.Copy()
+	state.EmptyTrash()
+	require.Equal(t, "changes the trash", describeChanges(before, state, "adds"))
+}
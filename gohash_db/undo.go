@@ -0,0 +1,244 @@
+package gohash_db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxUndo the maximum number of changes kept by an UndoHistory.
+const MaxUndo = 100
+
+// Change a change made to a State, which can be undone and redone.
+type Change struct {
+	// Description what made the change, e.g. the command that was run.
+	Description string
+	before      State
+	after       State
+}
+
+// UndoSummary describes what undoing the change does, e.g. "adds back 2 entries, removes 1 group".
+func (change *Change) UndoSummary() string {
+	return describeChanges(change.after, change.before, "adds back")
+}
+
+// RedoSummary describes what redoing the change does.
+func (change *Change) RedoSummary() string {
+	return describeChanges(change.before, change.after, "adds")
+}
+
+// UndoHistory keeps the changes made to a State, so that they can be undone and redone.
+//
+// The history only lives in memory, and it's lost when the database is closed.
+type UndoHistory struct {
+	undo []*Change
+	redo []*Change
+}
+
+// Record a change, given the state before and after the change, discarding all changes that could be redone.
+// The history keeps copies of the states, so they can be modified afterwards.
+//
+// Nothing is recorded if the states are the same, e.g. when only the master password was changed, or if only
+// HOTP counters were advanced, as one-time passwords can't be used again (see Undo).
+func (history *UndoHistory) Record(description string, before, after State) {
+	if sameState(before, after) || onlyCountersAdvanced(before, after) {
+		return
+	}
+	history.undo = append(history.undo, &Change{Description: description, before: before.Copy(), after: after.Copy()})
+	if len(history.undo) > MaxUndo {
+		history.undo = history.undo[len(history.undo)-MaxUndo:]
+	}
+	history.redo = nil
+}
+
+// NextUndo returns the change that would be undone by Undo, or nil if there's nothing to undo.
+func (history *UndoHistory) NextUndo() *Change {
+	if len(history.undo) == 0 {
+		return nil
+	}
+	return history.undo[len(history.undo)-1]
+}
+
+// NextRedo returns the change that would be redone by Redo, or nil if there's nothing to redo.
+func (history *UndoHistory) NextRedo() *Change {
+	if len(history.redo) == 0 {
+		return nil
+	}
+	return history.redo[len(history.redo)-1]
+}
+
+// Undo the last change, restoring the state as it was before the change.
+// HOTP counters are never rewound, so that one-time passwords that were already generated are not generated again.
+// Returns the change that was undone, or nil if there was nothing to undo.
+func (history *UndoHistory) Undo(state *State) *Change {
+	change := history.NextUndo()
+	if change != nil {
+		history.undo = history.undo[:len(history.undo)-1]
+		history.redo = append(history.redo, change)
+		*state = keepCounters(*state, change.before.Copy())
+	}
+	return change
+}
+
+// Redo the last change that was undone, restoring the state as it was after the change.
+// Returns the change that was redone, or nil if there was nothing to redo.
+func (history *UndoHistory) Redo(state *State) *Change {
+	change := history.NextRedo()
+	if change != nil {
+		history.redo = history.redo[:len(history.redo)-1]
+		history.undo = append(history.undo, change)
+		*state = keepCounters(*state, change.after.Copy())
+	}
+	return change
+}
+
+// Clear discards all changes, e.g. because the state was replaced.
+func (history *UndoHistory) Clear() {
+	history.undo = nil
+	history.redo = nil
+}
+
+// keepCounters advances the HOTP counters of the entries of the restored state to the counters of the same
+// entries (by ID) in the current state, if they're higher. Returns the restored state.
+func keepCounters(current, restored State) State {
+	counters := make(map[string]*LoginInfo)
+	for _, entries := range current {
+		for i := range entries {
+			if entries[i].OTP != nil && entries[i].OTP.Type == HOTP && entries[i].ID != "" {
+				counters[entries[i].ID] = &entries[i]
+			}
+		}
+	}
+	for _, entries := range restored {
+		for i := range entries {
+			entry := &entries[i]
+			if latest, ok := counters[entry.ID]; ok && entry.OTP != nil && entry.OTP.Type == HOTP &&
+				latest.OTP.Counter > entry.OTP.Counter {
+				entry.OTP.Counter = latest.OTP.Counter
+				if latest.UpdatedAt.After(entry.UpdatedAt) {
+					entry.UpdatedAt = latest.UpdatedAt
+				}
+			}
+		}
+	}
+	return restored
+}
+
+// onlyCountersAdvanced checks whether the only differences between two states are HOTP counters that were
+// advanced, and the update times of their entries.
+func onlyCountersAdvanced(before, after State) bool {
+	if len(before) != len(after) {
+		return false
+	}
+	for group, entries := range before {
+		other, exists := after[group]
+		if !exists || len(entries) != len(other) {
+			return false
+		}
+		for i := range entries {
+			if sameEntry(&entries[i], &other[i]) {
+				continue
+			}
+			entry := entries[i].Copy()
+			if entry.OTP == nil || other[i].OTP == nil || entry.OTP.Type != HOTP ||
+				other[i].OTP.Counter <= entry.OTP.Counter {
+				return false
+			}
+			entry.OTP.Counter, entry.UpdatedAt = other[i].OTP.Counter, other[i].UpdatedAt
+			if !sameEntry(&entry, &other[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sameState(a, b State) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for group, entries := range a {
+		other, exists := b[group]
+		if !exists || len(entries) != len(other) {
+			return false
+		}
+		for i := range entries {
+			if !sameEntry(&entries[i], &other[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// describeChanges describes the differences between two states, from the point of view of going from one
+// state to the other.
+func describeChanges(from, to State, adds string) string {
+	trashChanged := !sameState(State{TrashGroup: from[TrashGroup]}, State{TrashGroup: to[TrashGroup]})
+	from, to = from.withoutTrash(), to.withoutTrash()
+	fromIndex, toIndex := indexEntries(from), indexEntries(to)
+	var added, removed, changed, moved int
+	for key, loc := range toIndex {
+		previous, existed := fromIndex[key]
+		switch {
+		case !existed:
+			added++
+		case previous.group != loc.group:
+			moved++
+		case !sameEntry(previous.entry, loc.entry):
+			changed++
+		}
+	}
+	for key := range fromIndex {
+		if _, exists := toIndex[key]; !exists {
+			removed++
+		}
+	}
+	var addedGroups, removedGroups int
+	for group := range to {
+		if _, existed := from[group]; !existed {
+			addedGroups++
+		}
+	}
+	for group := range from {
+		if _, exists := to[group]; !exists {
+			removedGroups++
+		}
+	}
+
+	var parts []string
+	for _, part := range []struct {
+		count        int
+		verb, things string
+	}{
+		{added, adds, "entries"},
+		{removed, "removes", "entries"},
+		{changed, "changes", "entries"},
+		{moved, "moves", "entries"},
+		{addedGroups, adds, "groups"},
+		{removedGroups, "removes", "groups"},
+	} {
+		if part.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", part.verb, countOf(part.count, part.things)))
+		}
+	}
+	if len(parts) == 0 && trashChanged {
+		return "changes the trash"
+	}
+	if len(parts) == 0 {
+		return "no changes to entries or groups"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countOf formats a count of things, e.g. "1 entry" or "2 entries".
+func countOf(count int, things string) string {
+	if count != 1 {
+		return fmt.Sprintf("%d %s", count, things)
+	}
+	switch things {
+	case "entries":
+		return "1 entry"
+	default:
+		return "1 " + strings.TrimSuffix(things, "s")
+	}
+}
@@ -0,0 +1,110 @@
+package gohash_db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoAndRedo(t *testing.T) {
+	var history UndoHistory
+	state := State{"default": {{ID: "1", Name: "gmail"}}, "work": {{ID: "2", Name: "jira"}}}
+	original := state.Copy()
+	require.Nil(t, history.NextUndo())
+	require.Nil(t, history.Undo(&state))
+
+	before := state.Copy()
+	state.RemoveGroup("work")
+	history.Record("group -d work", before, state)
+
+	before = state.Copy()
+	state["default"][0].Password = "new password"
+	history.Record("entry -e gmail", before, state)
+	edited := state.Copy()
+
+	require.Equal(t, "entry -e gmail", history.NextUndo().Description)
+	require.Equal(t, "changes 1 entry", history.NextUndo().UndoSummary())
+	require.Equal(t, "entry -e gmail", history.Undo(&state).Description)
+	require.Equal(t, "", state["default"][0].Password)
+
+	require.Equal(t, "adds back 1 entry, adds back 1 group", history.NextUndo().UndoSummary())
+	history.Undo(&state)
+	require.Equal(t, original, state)
+	require.Nil(t, history.Undo(&state))
+
+	require.Equal(t, "removes 1 entry, removes 1 group", history.NextRedo().RedoSummary())
+	history.Redo(&state)
+	history.Redo(&state)
+	require.Equal(t, edited, state)
+	require.Nil(t, history.Redo(&state))
+
+	// the history is not affected by later modifications of the state
+	state["default"][0].Name = "changed"
+	history.Undo(&state)
+	require.Equal(t, "gmail", state["default"][0].Name)
+}
+
+func TestRecordingDiscardsRedo(t *testing.T) {
+	var history UndoHistory
+	state := State{"default": {}}
+	for i := 0; i < MaxUndo+5; i++ {
+		before := state.Copy()
+		state["default"] = append(state["default"], LoginInfo{ID: NewEntryID()})
+		history.Record("entry -c", before, state)
+	}
+	history.Undo(&state)
+	require.NotNil(t, history.NextRedo())
+
+	before := state.Copy()
+	state.AddGroup("work")
+	history.Record("group -c work", before, state)
+	require.Nil(t, history.NextRedo())
+
+	// changes that don't affect the state are not recorded
+	history.Record("cmp", state, state)
+	require.Equal(t, "group -c work", history.NextUndo().Description)
+
+	undone := 0
+	for history.Undo(&state) != nil {
+		undone++
+	}
+	require.Equal(t, MaxUndo, undone)
+	require.Len(t, state["default"], 5)
+}
+
+func TestUndoDoesNotRewindHOTPCounters(t *testing.T) {
+	var history UndoHistory
+	otp, err := ParseOTP("otpauth://hotp/Example:alice?secret=JBSWY3DPEHPK3PXP&counter=5")
+	require.NoError(t, err)
+	state := State{"default": {{ID: "1", Name: "gmail", OTP: otp}}}
+
+	before := state.Copy()
+	state["default"][0].Password = "new password"
+	history.Record("entry -e gmail", before, state)
+
+	// generating one-time passwords is not recorded
+	before = state.Copy()
+	_, _, err = state["default"][0].OTP.Code(time.Now())
+	require.NoError(t, err)
+	state["default"][0].UpdatedAt = time.Now()
+	history.Record("otp gmail", before, state)
+	require.Equal(t, "entry -e gmail", history.NextUndo().Description)
+
+	history.Undo(&state)
+	require.Equal(t, "", state["default"][0].Password)
+	require.Equal(t, uint64(6), state["default"][0].OTP.Counter)
+
+	_, _, err = state["default"][0].OTP.Code(time.Now())
+	require.NoError(t, err)
+	history.Redo(&state)
+	require.Equal(t, "new password", state["default"][0].Password)
+	require.Equal(t, uint64(7), state["default"][0].OTP.Counter)
+}
+
+func TestDescribeMovedEntries(t *testing.T) {
+	from := State{"default": {{ID: "1", Name: "gmail"}}, "work": {}}
+	to := State{"default": {}, "work": {{ID: "1", Name: "gmail"}}}
+	require.Equal(t, "moves 1 entry", describeChanges(from, to, "adds"))
+	require.Equal(t, "no changes to entries or groups", describeChanges(from, from, "adds"))
+}
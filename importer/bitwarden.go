@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+)
+
+// the types of Bitwarden items.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// the types of Bitwarden custom fields, other than text and boolean fields.
+const (
+	bitwardenHiddenField = 1
+	bitwardenLinkedField = 3
+)
+
+// bitwardenExport an unencrypted Bitwarden JSON export, of a personal vault or of an organization.
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Collections []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"collections"`
+	Items []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type          int      `json:"type"`
+	Name          string   `json:"name"`
+	Notes         string   `json:"notes"`
+	FolderID      string   `json:"folderId"`
+	CollectionIDs []string `json:"collectionIds"`
+	Fields        []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login struct {
+		URIs []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+	} `json:"login"`
+	PasswordHistory []struct {
+		Password     string `json:"password"`
+		LastUsedDate string `json:"lastUsedDate"`
+	} `json:"passwordHistory"`
+	CreationDate string `json:"creationDate"`
+	RevisionDate string `json:"revisionDate"`
+}
+
+// ReadBitwarden reads an unencrypted Bitwarden JSON export.
+//
+// Folders are imported as groups, with nested folders (e.g. 'work/aws') imported as nested groups.
+// Items of organization exports are imported into the group named after their first collection, and items
+// in no folder or collection into the default group. Logins and secure notes are imported as login and note
+// entries, with their custom fields, except for linked fields. Cards and identities are not imported.
+func ReadBitwarden(r io.Reader) (*Export, error) {
+	var file bitwardenExport
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("not a Bitwarden JSON export: %s", err.Error())
+	}
+	if file.Encrypted {
+		return nil, errors.New("the Bitwarden export is encrypted, export the vault in the (unencrypted) JSON format")
+	}
+	groups := make(map[string]string)
+	for _, folder := range file.Folders {
+		groups[folder.ID] = groupPath(strings.Split(folder.Name, gohash_db.GroupSeparator))
+	}
+	for _, collection := range file.Collections {
+		groups[collection.ID] = groupPath(strings.Split(collection.Name, gohash_db.GroupSeparator))
+	}
+	export := &Export{}
+	for i := range file.Items {
+		item := &file.Items[i]
+		group := gohash_db.DefaultGroup
+		if path, ok := groups[item.FolderID]; ok {
+			group = path
+		} else if len(item.CollectionIDs) > 0 {
+			if path, ok := groups[item.CollectionIDs[0]]; ok {
+				group = path
+			}
+		}
+		info, err := bitwardenInfo(item)
+		if err != nil {
+			name := item.Name
+			if name == "" {
+				name = "(untitled)"
+			}
+			export.Skipped = append(export.Skipped, Skipped{Item: fmt.Sprintf("item '%s'", name),
+				Reason: err.Error()})
+			continue
+		}
+		export.Entries = append(export.Entries, Entry{Group: group, Info: info})
+	}
+	return export, nil
+}
+
+func bitwardenInfo(item *bitwardenItem) (gohash_db.LoginInfo, error) {
+	var info gohash_db.LoginInfo
+	switch item.Type {
+	case bitwardenLogin:
+		info.Username, info.Password, info.Description = item.Login.Username, item.Login.Password, item.Notes
+		for i, uri := range item.Login.URIs {
+			if i == 0 {
+				info.URL = uri.URI
+			} else {
+				info.Fields = append(info.Fields, gohash_db.CustomField{Name: fmt.Sprintf("URL %d", i+1),
+					Value: uri.URI})
+			}
+		}
+	case bitwardenSecureNote:
+		info.Kind, info.Note = gohash_db.NoteKind, item.Notes
+	case bitwardenCard:
+		return info, errors.New("cards are not supported")
+	case bitwardenIdentity:
+		return info, errors.New("identities are not supported")
+	default:
+		return info, fmt.Errorf("unknown item type: %d", item.Type)
+	}
+	if info.Name = entryName(item.Name, info.URL); info.Name == "" {
+		return info, errors.New("it has no name")
+	}
+	for _, field := range item.Fields {
+		if field.Type == bitwardenLinkedField || field.Name == "" {
+			continue
+		}
+		info.Fields = append(info.Fields, gohash_db.CustomField{Name: field.Name, Value: field.Value,
+			Secret: field.Type == bitwardenHiddenField})
+	}
+	if item.Login.TOTP != "" {
+		otp, err := gohash_db.ParseOTP(item.Login.TOTP)
+		if err == nil {
+			info.OTP = otp
+		} else {
+			info.Fields = append(info.Fields, gohash_db.CustomField{Name: "TOTP", Value: item.Login.TOTP,
+				Secret: true})
+		}
+	}
+	for _, record := range item.PasswordHistory {
+		if len(info.PasswordHistory) < gohash_db.MaxPasswordHistory {
+			info.PasswordHistory = append(info.PasswordHistory, gohash_db.PasswordRecord{
+				Password: record.Password, ReplacedAt: parseTime(record.LastUsedDate)})
+		}
+	}
+	info.CreatedAt = parseTime(item.CreationDate)
+	info.UpdatedAt = parseTime(item.RevisionDate)
+	return info, nil
+}
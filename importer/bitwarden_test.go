@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBitwarden(t *testing.T) {
+	export := readFixture(t, "bitwarden.json", ReadBitwarden)
+
+	require.Len(t, export.Entries, 3)
+
+	console := export.Entries[0]
+	require.Equal(t, "Work/AWS", console.Group)
+	require.Equal(t, "console", console.Info.Name)
+	require.Equal(t, "admin", console.Info.Username)
+	require.Equal(t, "aws-pass", console.Info.Password)
+	require.Equal(t, "https://console.aws.amazon.com", console.Info.URL)
+	require.Equal(t, "production account", console.Info.Description)
+	require.Equal(t, []gohash_db.CustomField{
+		{Name: "URL 2", Value: "https://signin.aws.amazon.com"},
+		{Name: "account id", Value: "123456789012"},
+		{Name: "PIN", Value: "4321", Secret: true},
+	}, console.Info.Fields)
+	require.NotNil(t, console.Info.OTP)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", console.Info.OTP.Secret)
+	require.Equal(t, []gohash_db.PasswordRecord{
+		{Password: "older-pass", ReplacedAt: time.Date(2020, 12, 1, 10, 0, 0, 0, time.UTC)},
+	}, console.Info.PasswordHistory)
+	require.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), console.Info.CreatedAt)
+	require.Equal(t, time.Date(2021, 2, 3, 4, 5, 6, 789000000, time.UTC), console.Info.UpdatedAt)
+
+	github := export.Entries[1]
+	require.Equal(t, "default", github.Group)
+	require.Equal(t, "github.com", github.Info.Name)
+
+	wifi := export.Entries[2]
+	require.Equal(t, "Personal", wifi.Group)
+	require.Equal(t, gohash_db.NoteKind, wifi.Info.Kind)
+	require.Equal(t, "network: home\npassword: secret", wifi.Info.Note)
+
+	require.Equal(t, []Skipped{{Item: "item 'visa'", Reason: "cards are not supported"}}, export.Skipped)
+}
+
+func TestReadEncryptedBitwarden(t *testing.T) {
+	_, err := ReadBitwarden(strings.NewReader(`{"encrypted": true, "encKeyValidation_DO_NOT_EDIT": "x"}`))
+	require.EqualError(t, err,
+		"the Bitwarden export is encrypted, export the vault in the (unencrypted) JSON format")
+}
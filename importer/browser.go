@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+)
+
+// browserColumns the names of the columns of the CSV exports of Chrome and Firefox, in lower case.
+var browserColumns = map[string][]string{
+	"name":                {"name"},
+	"url":                 {"url"},
+	"username":            {"username"},
+	"password":            {"password"},
+	"note":                {"note"},
+	"timecreated":         {"timecreated"},
+	"timepasswordchanged": {"timepasswordchanged"},
+}
+
+// secondLevelDomains common second-level domains under which domains are registered, as in example.co.uk.
+var secondLevelDomains = map[string]bool{
+	"ac": true, "co": true, "com": true, "edu": true, "gov": true, "govt": true, "net": true, "ne": true,
+	"or": true, "org": true,
+}
+
+// ReadBrowserCSV reads the CSV export of the passwords saved by Chrome, Firefox and other browsers, with the
+// columns url, username and password, and optionally name and note (Chrome), or timeCreated and
+// timePasswordChanged (Firefox).
+//
+// Entries are named after the host of their URL, without 'www.', followed by their username in parentheses
+// if more than one entry has the same host. They're imported into groups named after the registrable domain
+// of their URL (see RegistrableDomain), e.g. login.example.co.uk is imported into the group example.co.uk.
+func ReadBrowserCSV(r io.Reader) (*Export, error) {
+	export := &Export{}
+	var rows []csvRow
+	hosts := make(map[string]int)
+	err := readCSV(r, "browser", browserColumns, []string{"url", "username", "password"}, export,
+		func(row csvRow) {
+			rows = append(rows, row)
+			hosts[browserHost(row.values["url"])]++
+		})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		value := row.values
+		host := browserHost(value["url"])
+		info := gohash_db.LoginInfo{Name: host, URL: value["url"], Username: value["username"],
+			Password: value["password"], Description: value["note"]}
+		if host == "" {
+			// entries without a host may still have a name, in Chrome exports
+			info.Name = strings.TrimSpace(value["name"])
+		} else if hosts[host] > 1 && info.Username != "" {
+			info.Name = fmt.Sprintf("%s (%s)", host, info.Username)
+		}
+		if info.Name == "" {
+			export.Skipped = append(export.Skipped, Skipped{Item: row.item(), Reason: "it has no URL"})
+			continue
+		}
+		info.CreatedAt = unixMilliTime(value["timecreated"])
+		info.UpdatedAt = unixMilliTime(value["timepasswordchanged"])
+		group := gohash_db.DefaultGroup
+		if domain := RegistrableDomain(host); domain != "" {
+			group = groupPath([]string{domain})
+		}
+		export.Entries = append(export.Entries, Entry{Group: group, Info: info})
+	}
+	return export, nil
+}
+
+// browserHost returns the host of a URL, without 'www.'.
+func browserHost(rawURL string) string {
+	return strings.TrimPrefix(strings.ToLower(urlHost(rawURL)), "www.")
+}
+
+// RegistrableDomain returns the domain of a host that was registered with a domain registrar, e.g. example.com
+// for login.example.com, or example.co.uk for www.example.co.uk. IP addresses and single-label hosts, such as
+// localhost, are returned as they are.
+//
+// Only the most common second-level domains, such as co.uk, are recognized, so hosts under other public
+// suffixes may give a domain that's too short.
+func RegistrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	n := 2
+	if len(labels) > 2 && len(labels[len(labels)-1]) == 2 && secondLevelDomains[labels[len(labels)-2]] {
+		n = 3 // a country-code domain like example.co.uk
+	}
+	if len(labels) <= n {
+		return host
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
+
+// unixMilliTime parses a timestamp given as the number of milliseconds since the Unix epoch, returning the zero
+// time if it can't be parsed.
+func unixMilliTime(text string) time.Time {
+	millis, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil || millis <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond)).UTC()
+}
@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadChromeCSV(t *testing.T) {
+	export := readFixture(t, "chrome.csv", ReadBrowserCSV)
+
+	require.Equal(t, []Entry{
+		{Group: "google.com", Info: gohash_db.LoginInfo{Name: "accounts.google.com",
+			URL: "https://accounts.google.com/signin/v2", Username: "joe@gmail.com", Password: "g-pass"}},
+		{Group: "amazon.co.uk", Info: gohash_db.LoginInfo{Name: "amazon.co.uk (joe)",
+			URL: "https://www.amazon.co.uk/ap/signin", Username: "joe", Password: "uk-pass",
+			Description: "prime account"}},
+		{Group: "amazon.co.uk", Info: gohash_db.LoginInfo{Name: "amazon.co.uk (mary)",
+			URL: "https://www.amazon.co.uk/ap/signin", Username: "mary", Password: "mary-pass"}},
+		{Group: "192.168.0.1", Info: gohash_db.LoginInfo{Name: "192.168.0.1",
+			URL: "http://192.168.0.1/", Username: "admin", Password: "admin"}},
+		{Group: "default", Info: gohash_db.LoginInfo{Name: "localapp", Username: "nobody", Password: "x"}},
+	}, export.Entries)
+	require.Equal(t, []Skipped{{Item: "line 7", Reason: "it has no URL"}}, export.Skipped)
+}
+
+func TestReadFirefoxCSV(t *testing.T) {
+	export := readFixture(t, "firefox.csv", ReadBrowserCSV)
+
+	require.Len(t, export.Entries, 2)
+
+	github := export.Entries[0]
+	require.Equal(t, "github.com", github.Group)
+	require.Equal(t, "github.com", github.Info.Name)
+	require.Equal(t, "gh-pass", github.Info.Password)
+	require.Equal(t, time.Unix(1600000000, 123000000).UTC(), github.Info.CreatedAt)
+	require.Equal(t, time.Unix(1620000000, 456000000).UTC(), github.Info.UpdatedAt)
+
+	example := export.Entries[1]
+	require.Equal(t, "example.com", example.Group)
+	require.Equal(t, "login.example.com", example.Info.Name)
+	require.Empty(t, export.Skipped)
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	for host, domain := range map[string]string{
+		"example.com":            "example.com",
+		"login.example.com":      "example.com",
+		"a.b.example.com.":       "example.com",
+		"www.example.co.uk":      "example.co.uk",
+		"shop.example.com.au":    "example.com.au",
+		"example.co.uk":          "example.co.uk",
+		"mail.example.de":        "example.de",
+		"localhost":              "localhost",
+		"10.0.0.1":               "10.0.0.1",
+		"::1":                    "::1",
+		"Accounts.Google.COM":    "google.com",
+		"id.service.example.org": "example.org",
+	} {
+		require.Equal(t, domain, RegistrableDomain(host), host)
+	}
+}
+
+func TestMoveTo(t *testing.T) {
+	export := readFixture(t, "chrome.csv", ReadBrowserCSV)
+	export.MoveTo("web")
+	for _, entry := range export.Entries {
+		require.Equal(t, "web", entry.Group)
+	}
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// csvRow a row of a CSV export.
+type csvRow struct {
+	line int
+	// values the values of the row, by column.
+	values map[string]string
+}
+
+func (row csvRow) item() string {
+	return fmt.Sprintf("line %d", row.line)
+}
+
+// readCSV reads the rows of a CSV export of the given format, whose first row is a header naming its columns.
+//
+// The columns map gives the names each column may have in the header, in lower case, and an error is returned
+// if any of the required columns is missing. The rows are given to the read function, in order, except for rows
+// with fewer values than the header, which are added to the Skipped items of the export.
+func readCSV(r io.Reader, format string, columns map[string][]string, required []string, export *Export,
+	read func(row csvRow)) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	reader := csv.NewReader(bytes.NewReader(trimBOM(data)))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("not a %s CSV export: %s", format, err.Error())
+	}
+	indexes := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, names := range columns {
+			for _, candidate := range names {
+				if _, found := indexes[column]; !found && name == candidate {
+					indexes[column] = i
+				}
+			}
+		}
+	}
+	for _, column := range required {
+		if _, ok := indexes[column]; !ok {
+			return fmt.Errorf("not a %s CSV export: missing the '%s' column", format, column)
+		}
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		row := csvRow{line: line, values: make(map[string]string, len(indexes))}
+		if len(record) < len(header) {
+			export.Skipped = append(export.Skipped, Skipped{Item: row.item(),
+				Reason: fmt.Sprintf("expected %d columns, found %d", len(header), len(record))})
+			continue
+		}
+		for column, i := range indexes {
+			row.values[column] = record[i]
+		}
+		read(row)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Package importer reads the files exported by other password managers, and adds their entries to
+// a go-hash database.
+//
+// Each supported format has a Read function returning an Export, which holds the entries read from the file
+// and the items of the file that could not be imported. Import then adds the entries of an Export to a State.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+)
+
+// Entry an entry read from an export file.
+type Entry struct {
+	// Group the path of the group the entry should be added to.
+	Group string
+	Info  gohash_db.LoginInfo
+}
+
+// Skipped an item of an export file that was not imported.
+type Skipped struct {
+	// Item describes the item, e.g. its title or the line of the file it was found in.
+	Item   string
+	Reason string
+}
+
+// Export the entries read from an export file.
+type Export struct {
+	Entries []Entry
+	Skipped []Skipped
+}
+
+// MoveTo makes all entries of the export be imported into the given group, instead of the groups they were in.
+func (export *Export) MoveTo(group string) {
+	for i := range export.Entries {
+		export.Entries[i].Group = group
+	}
+}
+
+// Policy what to do with an imported entry whose name is already taken in its group, by an existing entry
+// or by another imported entry.
+type Policy uint8
+
+const (
+	// SkipDuplicates does not import the entry. This is the default policy.
+	SkipDuplicates Policy = iota
+
+	// RenameDuplicates imports the entry with a unique name (see gohash_db.State.UniqueEntryName).
+	RenameDuplicates
+
+	// OverwriteDuplicates moves the existing entry to the trash (see gohash_db.State.DeleteEntry), so that it can
+	// be restored, and imports the entry in its place.
+	OverwriteDuplicates
+)
+
+func (policy Policy) String() string {
+	switch policy {
+	case SkipDuplicates:
+		return "skip"
+	case RenameDuplicates:
+		return "rename"
+	case OverwriteDuplicates:
+		return "overwrite"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(policy))
+	}
+}
+
+// ParsePolicy parses the name of a Policy: skip, rename or overwrite.
+func ParsePolicy(name string) (Policy, error) {
+	switch strings.ToLower(name) {
+	case "skip":
+		return SkipDuplicates, nil
+	case "rename":
+		return RenameDuplicates, nil
+	case "overwrite":
+		return OverwriteDuplicates, nil
+	default:
+		return SkipDuplicates, fmt.Errorf("unknown conflict policy '%s' (use skip, rename or overwrite)", name)
+	}
+}
+
+// Duplicate an entry whose name was already taken in its group when it was imported.
+type Duplicate struct {
+	Entry
+	// Resolution what was done with the entry.
+	Resolution Policy
+	// NewName the name the entry was given, if it was renamed.
+	NewName string
+}
+
+// Report the result of importing the entries of an Export.
+type Report struct {
+	// Imported the locations of the imported entries, in the order they were imported. Entries that overwrote
+	// existing entries are included, but not skipped duplicates, nor imported entries overwritten by later ones.
+	Imported []gohash_db.EntryRef
+	// Groups the paths of the groups created to hold the imported entries.
+	Groups []string
+	// Duplicates the entries whose names were already taken in their groups, handled according to the Policy.
+	Duplicates []Duplicate
+	// Skipped the items of the export file that could not be read as entries.
+	Skipped []Skipped
+}
+
+// Import adds the entries of an export to the state, creating their groups if necessary.
+//
+// Imported entries are given new IDs. Entries without timestamps are considered to have been created,
+// and last updated, at the given time. Entries whose names are already taken in their group, by an existing
+// entry or by another imported entry, are handled according to the given policy, and reported as duplicates.
+func Import(state gohash_db.State, export *Export, now time.Time, policy Policy) Report {
+	report := Report{Skipped: export.Skipped}
+	for _, entry := range export.Entries {
+		info := entry.Info.Copy()
+		info.ID = gohash_db.NewEntryID()
+		if info.UpdatedAt.IsZero() {
+			info.UpdatedAt = now
+		}
+		if info.CreatedAt.IsZero() {
+			info.CreatedAt = info.UpdatedAt
+		}
+		if i, exists := state.FindEntry(entry.Group, info.Name); exists {
+			duplicate := Duplicate{Entry: entry, Resolution: policy}
+			switch policy {
+			case RenameDuplicates:
+				info.Name = state.UniqueEntryName(entry.Group, info.Name)
+				duplicate.NewName = info.Name
+			case OverwriteDuplicates:
+				ref := gohash_db.EntryRef{Group: entry.Group, Index: i}
+				state.DeleteEntry(ref, now)
+				report.Imported = removeRef(report.Imported, ref)
+			}
+			report.Duplicates = append(report.Duplicates, duplicate)
+			if policy == SkipDuplicates {
+				continue
+			}
+		}
+		if !state.GroupExists(entry.Group) {
+			report.Groups = append(report.Groups, entry.Group)
+		}
+		state.AddGroup(entry.Group)
+		state[entry.Group] = append(state[entry.Group], info)
+		report.Imported = append(report.Imported,
+			gohash_db.EntryRef{Group: entry.Group, Index: len(state[entry.Group]) - 1})
+	}
+	return report
+}
+
+// removeRef removes a reference to an entry that was removed from its group, and updates the references to the
+// entries that followed it in the group.
+func removeRef(refs []gohash_db.EntryRef, removed gohash_db.EntryRef) []gohash_db.EntryRef {
+	result := refs[:0]
+	for _, ref := range refs {
+		if ref == removed {
+			continue
+		}
+		if ref.Group == removed.Group && ref.Index > removed.Index {
+			ref.Index--
+		}
+		result = append(result, ref)
+	}
+	return result
+}
+
+// newEntry creates an entry with the given values, as exported by most password managers. Entries with only
+// notes are created as notes, and the notes of other entries become their description. The otp value may be an
+// otpauth URI or a base32-encoded secret (see gohash_db.ParseOTP), and it's kept as a custom field if it's invalid.
+func newEntry(title, username, password, rawURL, notes, otp string) (gohash_db.LoginInfo, error) {
+	info := gohash_db.LoginInfo{Name: entryName(title, rawURL), Username: username, Password: password,
+		URL: rawURL}
+	if info.Name == "" {
+		return info, errors.New("it has no title")
+	}
+	if username == "" && password == "" && rawURL == "" && notes != "" {
+		info.Kind = gohash_db.NoteKind
+		info.Note = notes
+	} else {
+		info.Description = notes
+	}
+	if otp != "" {
+		var err error
+		if info.OTP, err = gohash_db.ParseOTP(otp); err != nil {
+			// keep the value, so that it's not lost
+			info.Fields = append(info.Fields, gohash_db.CustomField{Name: "otp", Value: otp, Secret: true})
+			info.OTP = nil
+		}
+	}
+	return info, nil
+}
+
+// groupPath returns the path of the go-hash group with the given (nested) group names, or the default group
+// if there are no names.
+func groupPath(names []string) string {
+	var cleanNames []string
+	for _, name := range names {
+		// ':' and '/' are not allowed in group names
+		name = strings.TrimSpace(strings.NewReplacer(":", "-", gohash_db.GroupSeparator, "-").Replace(name))
+		if name == "" {
+			name = "unnamed"
+		}
+		cleanNames = append(cleanNames, name)
+	}
+	if len(cleanNames) == 0 {
+		return gohash_db.DefaultGroup
+	}
+	return strings.Join(cleanNames, gohash_db.GroupSeparator)
+}
+
+// entryName returns the name of an entry with the given title, which defaults to the host of its URL.
+// Returns an empty name if neither is available.
+func entryName(title, rawURL string) string {
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	return urlHost(rawURL)
+}
+
+// urlHost returns the host of a URL, which may not include the scheme.
+func urlHost(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+// addTags adds the given tags to an entry, replacing spaces with '-', and ignoring invalid tags.
+func addTags(info *gohash_db.LoginInfo, tags []string) {
+	for _, tag := range tags {
+		_, _ = info.AddTag(strings.Join(strings.Fields(tag), "-"))
+	}
+}
+
+// timeLayouts the layouts of the timestamps found in export files.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// parseTime parses a timestamp, returning the zero time if it can't be parsed.
+func parseTime(text string) time.Time {
+	text = strings.TrimSpace(text)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// trimBOM removes the byte order mark some applications write at the start of UTF-8 files.
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
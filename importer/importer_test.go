@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	importTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	updatedAt  = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func importDB() gohash_db.State {
+	return gohash_db.State{
+		"default": {{ID: "1", Name: "gmail", Password: "old", CreatedAt: updatedAt}},
+		"work":    {},
+	}
+}
+
+func importExport() *Export {
+	return &Export{
+		Entries: []Entry{
+			{Group: "default", Info: gohash_db.LoginInfo{Name: "gmail", Password: "new"}},
+			{Group: "work/aws", Info: gohash_db.LoginInfo{Name: "console", UpdatedAt: updatedAt}},
+			{Group: "work/aws", Info: gohash_db.LoginInfo{Name: "console", Password: "other"}},
+			{Group: "work", Info: gohash_db.LoginInfo{Name: "jira"}},
+		},
+		Skipped: []Skipped{{Item: "line 3", Reason: "it has no title"}},
+	}
+}
+
+func TestImportSkippingDuplicates(t *testing.T) {
+	state, export := importDB(), importExport()
+
+	report := Import(state, export, importTime, SkipDuplicates)
+
+	require.Equal(t, []gohash_db.EntryRef{{Group: "work/aws", Index: 0}, {Group: "work", Index: 0}}, report.Imported)
+	require.Equal(t, []string{"work/aws"}, report.Groups)
+	require.Equal(t, []Duplicate{
+		{Entry: export.Entries[0], Resolution: SkipDuplicates},
+		{Entry: export.Entries[2], Resolution: SkipDuplicates},
+	}, report.Duplicates)
+	require.Equal(t, export.Skipped, report.Skipped)
+
+	// existing entries are not changed
+	require.Equal(t, "old", state["default"][0].Password)
+
+	console := state["work/aws"][0]
+	require.NotEmpty(t, console.ID)
+	require.Equal(t, updatedAt, console.CreatedAt)
+	require.Equal(t, updatedAt, console.UpdatedAt)
+
+	jira := state["work"][0]
+	require.NotEqual(t, console.ID, jira.ID)
+	require.Equal(t, importTime, jira.CreatedAt)
+	require.Equal(t, importTime, jira.UpdatedAt)
+}
+
+func TestImportRenamingDuplicates(t *testing.T) {
+	state, export := importDB(), importExport()
+
+	report := Import(state, export, importTime, RenameDuplicates)
+
+	require.Equal(t, []gohash_db.EntryRef{{Group: "default", Index: 1}, {Group: "work/aws", Index: 0},
+		{Group: "work/aws", Index: 1}, {Group: "work", Index: 0}}, report.Imported)
+	require.Equal(t, []Duplicate{
+		{Entry: export.Entries[0], Resolution: RenameDuplicates, NewName: "gmail (copy)"},
+		{Entry: export.Entries[2], Resolution: RenameDuplicates, NewName: "console (copy)"},
+	}, report.Duplicates)
+	require.Equal(t, "old", state["default"][0].Password)
+	require.Equal(t, "new", state["default"][1].Password)
+	require.Equal(t, "console (copy)", state["work/aws"][1].Name)
+	require.Equal(t, "other", state["work/aws"][1].Password)
+}
+
+func TestImportOverwritingDuplicates(t *testing.T) {
+	state, export := importDB(), importExport()
+	gmail := state["default"][0]
+	gmail.Tags = []string{"mail"}
+	gmail.Fields = []gohash_db.CustomField{{Name: "pin", Value: "1234"}}
+	state["default"][0] = gmail
+
+	report := Import(state, export, importTime, OverwriteDuplicates)
+
+	require.Equal(t, []gohash_db.EntryRef{{Group: "default", Index: 0}, {Group: "work/aws", Index: 0},
+		{Group: "work", Index: 0}}, report.Imported)
+	require.Len(t, report.Duplicates, 2)
+	require.Len(t, state["default"], 1)
+	require.Len(t, state["work/aws"], 1)
+
+	imported := state["default"][0]
+	require.NotEqual(t, gmail.ID, imported.ID)
+	require.Equal(t, "new", imported.Password)
+	require.Equal(t, importTime, imported.CreatedAt)
+	require.Empty(t, imported.Tags)
+	require.Equal(t, "other", state["work/aws"][0].Password)
+
+	// overwritten entries are moved to the trash, with everything they had
+	require.Len(t, state[gohash_db.TrashGroup], 2)
+	trashed := state[gohash_db.TrashGroup][0]
+	require.Equal(t, &gohash_db.Deletion{DeletedAt: importTime, Group: "default"}, trashed.Deletion)
+	trashed.Deletion = nil
+	require.Equal(t, gmail, trashed)
+	require.Equal(t, "console", state[gohash_db.TrashGroup][1].Name)
+	require.Equal(t, "work/aws", state[gohash_db.TrashGroup][1].Deletion.Group)
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, policy := range []Policy{SkipDuplicates, RenameDuplicates, OverwriteDuplicates} {
+		parsed, err := ParsePolicy(policy.String())
+		require.NoError(t, err)
+		require.Equal(t, policy, parsed)
+	}
+	_, err := ParsePolicy("merge")
+	require.EqualError(t, err, "unknown conflict policy 'merge' (use skip, rename or overwrite)")
+}
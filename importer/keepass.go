@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+)
+
+// keePassRecycleBin the name KeePass and KeePassXC give to the group holding deleted entries.
+const keePassRecycleBin = "Recycle Bin"
+
+// keePassFile the root element of a KeePass 2 XML export.
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBinEnabled string
+		RecycleBinUUID    string
+	}
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	}
+}
+
+type keePassGroup struct {
+	UUID    string
+	Name    string
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+// keePassEntry an entry of a KeePass 2 XML export. The previous versions of the entry, in its History,
+// are not imported.
+type keePassEntry struct {
+	Tags  string
+	Times struct {
+		CreationTime         string
+		LastModificationTime string
+	}
+	Strings []struct {
+		Key   string
+		Value struct {
+			Text string `xml:",chardata"`
+			// Protected values are encrypted with a key stored in the database file, so they can't be read.
+			Protected string `xml:",attr"`
+			// ProtectInMemory values are not encrypted, but are secret.
+			ProtectInMemory string `xml:",attr"`
+		}
+	} `xml:"String"`
+}
+
+// ReadKeePass reads a KeePass 2 XML export, or a KeePassXC CSV export, detecting the format from its contents.
+func ReadKeePass(r io.Reader) (*Export, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(trimBOM(data)), []byte("<")) {
+		return ReadKeePassXML(bytes.NewReader(data))
+	}
+	return ReadKeePassCSV(bytes.NewReader(data))
+}
+
+// ReadKeePassXML reads a KeePass 2 XML export.
+//
+// The top-level group of the export is the database itself, so its entries are imported into the default
+// group, and its sub-groups become top-level groups. The Title, UserName, Password, URL and Notes of
+// entries are imported as the name, username, password, URL and description of login entries, except for
+// entries with only Notes, which are imported as notes. Other values become custom fields, except for
+// 'otp' values, which are used to generate one-time passwords. Entries in the recycle bin are not imported.
+func ReadKeePassXML(r io.Reader) (*Export, error) {
+	var file keePassFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("not a KeePass 2 XML export: %s", err.Error())
+	}
+	recycleBin := ""
+	if strings.EqualFold(file.Meta.RecycleBinEnabled, "true") {
+		recycleBin = file.Meta.RecycleBinUUID
+	}
+	export := &Export{}
+	var readGroup func(group *keePassGroup, names []string)
+	readGroup = func(group *keePassGroup, names []string) {
+		path := groupPath(names)
+		for i := range group.Entries {
+			keePassXMLEntry(&group.Entries[i], path, export)
+		}
+		for i := range group.Groups {
+			subGroup := &group.Groups[i]
+			if recycleBin != "" && subGroup.UUID == recycleBin {
+				export.Skipped = append(export.Skipped, Skipped{Item: fmt.Sprintf("group '%s'", subGroup.Name),
+					Reason: "the recycle bin is not imported"})
+				continue
+			}
+			readGroup(subGroup, append(names[:len(names):len(names)], subGroup.Name))
+		}
+	}
+	for i := range file.Root.Groups {
+		readGroup(&file.Root.Groups[i], nil)
+	}
+	return export, nil
+}
+
+func keePassXMLEntry(entry *keePassEntry, group string, export *Export) {
+	values := make(map[string]string)
+	var fields []gohash_db.CustomField
+	for _, str := range entry.Strings {
+		if strings.EqualFold(str.Value.Protected, "true") && str.Value.Text != "" {
+			export.Skipped = append(export.Skipped, Skipped{Item: keePassItem(group, entry),
+				Reason: "its values are encrypted, export the database to XML from KeePass, not from a KDBX file"})
+			return
+		}
+		switch str.Key {
+		case "Title", "UserName", "Password", "URL", "Notes", "otp":
+			values[str.Key] = str.Value.Text
+		default:
+			if str.Value.Text != "" {
+				fields = append(fields, gohash_db.CustomField{Name: str.Key, Value: str.Value.Text,
+					Secret: strings.EqualFold(str.Value.ProtectInMemory, "true")})
+			}
+		}
+	}
+	info, err := newEntry(values["Title"], values["UserName"], values["Password"], values["URL"],
+		values["Notes"], values["otp"])
+	if err != nil {
+		export.Skipped = append(export.Skipped, Skipped{Item: keePassItem(group, entry), Reason: err.Error()})
+		return
+	}
+	info.Fields = append(fields, info.Fields...)
+	info.CreatedAt = keePassTime(entry.Times.CreationTime)
+	info.UpdatedAt = keePassTime(entry.Times.LastModificationTime)
+	addTags(&info, strings.FieldsFunc(entry.Tags, func(c rune) bool { return c == ';' || c == ',' }))
+	export.Entries = append(export.Entries, Entry{Group: group, Info: info})
+}
+
+// keePassItem describes an entry of a KeePass export that could not be imported.
+func keePassItem(group string, entry *keePassEntry) string {
+	for _, str := range entry.Strings {
+		if str.Key == "Title" && str.Value.Text != "" && !strings.EqualFold(str.Value.Protected, "true") {
+			return fmt.Sprintf("entry '%s:%s'", group, str.Value.Text)
+		}
+	}
+	return fmt.Sprintf("untitled entry in group '%s'", group)
+}
+
+// keePassTime parses a KeePass timestamp, which is either given in the ISO-8601 format or, in KDBX 4 databases,
+// as the base64-encoded number of seconds since 0001-01-01.
+func keePassTime(text string) time.Time {
+	if t := parseTime(text); !t.IsZero() {
+		return t
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil || len(data) != 8 {
+		return time.Time{}
+	}
+	const unixEpochSeconds = 62135596800 // seconds from 0001-01-01 to 1970-01-01
+	return time.Unix(int64(binary.LittleEndian.Uint64(data))-unixEpochSeconds, 0).UTC()
+}
+
+// keePassColumns the names of the columns of a KeePassXC CSV export, in lower case.
+var keePassColumns = map[string][]string{
+	"group":         {"group"},
+	"title":         {"title"},
+	"username":      {"username"},
+	"password":      {"password"},
+	"url":           {"url"},
+	"notes":         {"notes"},
+	"totp":          {"totp"},
+	"last modified": {"last modified"},
+	"created":       {"created"},
+}
+
+// ReadKeePassCSV reads a KeePassXC CSV export, whose columns are given in its header: Group, Title, Username,
+// Password, URL, Notes and, in recent versions of KeePassXC, TOTP, Last Modified and Created.
+//
+// Entries are mapped as in ReadKeePassXML. The first group of every path is the database itself, and entries in
+// the top-level recycle bin group are not imported.
+func ReadKeePassCSV(r io.Reader) (*Export, error) {
+	export := &Export{}
+	err := readCSV(r, "KeePassXC", keePassColumns, []string{"group", "title", "password"}, export, func(row csvRow) {
+		value := row.values
+		names := strings.Split(value["group"], gohash_db.GroupSeparator)[1:]
+		if len(names) == 1 && names[0] == keePassRecycleBin {
+			export.Skipped = append(export.Skipped, Skipped{Item: fmt.Sprintf("%s (%s)", row.item(), value["title"]),
+				Reason: "the recycle bin is not imported"})
+			return
+		}
+		info, err := newEntry(value["title"], value["username"], value["password"], value["url"],
+			value["notes"], value["totp"])
+		if err != nil {
+			export.Skipped = append(export.Skipped, Skipped{Item: row.item(), Reason: err.Error()})
+			return
+		}
+		info.CreatedAt = parseTime(value["created"])
+		info.UpdatedAt = parseTime(value["last modified"])
+		export.Entries = append(export.Entries, Entry{Group: groupPath(names), Info: info})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
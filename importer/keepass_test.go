@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/stretchr/testify/require"
+)
+
+func readFixture(t *testing.T, name string, read func(r io.Reader) (*Export, error)) *Export {
+	file, err := os.Open("testdata/" + name)
+	require.NoError(t, err)
+	defer file.Close()
+	export, err := read(file)
+	require.NoError(t, err)
+	return export
+}
+
+func TestReadKeePassXML(t *testing.T) {
+	export := readFixture(t, "keepass.xml", ReadKeePass)
+
+	require.Len(t, export.Entries, 5)
+
+	gmail := export.Entries[0]
+	require.Equal(t, "default", gmail.Group)
+	require.Equal(t, "gmail", gmail.Info.Name)
+	require.Equal(t, gohash_db.LoginKind, gmail.Info.Kind)
+	require.Equal(t, "joe@gmail.com", gmail.Info.Username)
+	require.Equal(t, "p4ss&word", gmail.Info.Password)
+	require.Equal(t, "https://mail.google.com", gmail.Info.URL)
+	require.Equal(t, "personal mail", gmail.Info.Description)
+	require.Equal(t, []string{"mail", "personal"}, gmail.Info.Tags)
+	require.Equal(t, time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC), gmail.Info.CreatedAt)
+	require.Equal(t, time.Date(2020, 6, 2, 9, 30, 0, 0, time.UTC), gmail.Info.UpdatedAt)
+	require.NotNil(t, gmail.Info.OTP)
+	require.Equal(t, "Google", gmail.Info.OTP.Issuer)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", gmail.Info.OTP.Secret)
+	require.Empty(t, gmail.Info.Fields)
+
+	// KDBX 4 timestamps, and custom fields
+	jira := export.Entries[1]
+	require.Equal(t, "Work", jira.Group)
+	require.Equal(t, "jira", jira.Info.Name)
+	require.Equal(t, time.Date(2021, 3, 4, 10, 20, 30, 0, time.UTC), jira.Info.UpdatedAt)
+	require.Equal(t, []gohash_db.CustomField{
+		{Name: "PIN", Value: "1234", Secret: true},
+		{Name: "Team", Value: "backend"},
+	}, jira.Info.Fields)
+
+	// duplicates are only detected when importing
+	require.Equal(t, "Work", export.Entries[2].Group)
+	require.Equal(t, "jira", export.Entries[2].Info.Name)
+
+	// invalid characters are replaced in group names, and entries with only notes become notes
+	codes := export.Entries[3]
+	require.Equal(t, "Work/AWS- prod-eu", codes.Group)
+	require.Equal(t, gohash_db.NoteKind, codes.Info.Kind)
+	require.Equal(t, "1111\n2222", codes.Info.Note)
+
+	// entries without a title are named after the host of their URL
+	require.Equal(t, "console.aws.amazon.com", export.Entries[4].Info.Name)
+	require.Equal(t, "aws-pass", export.Entries[4].Info.Password)
+
+	require.Equal(t, []Skipped{
+		{Item: "untitled entry in group 'Work/AWS- prod-eu'", Reason: "it has no title"},
+		{Item: "entry 'Work/AWS- prod-eu:vault'",
+			Reason: "its values are encrypted, export the database to XML from KeePass, not from a KDBX file"},
+		{Item: "group 'Recycle Bin'", Reason: "the recycle bin is not imported"},
+	}, export.Skipped)
+}
+
+func TestReadKeePassCSV(t *testing.T) {
+	export := readFixture(t, "keepassxc.csv", ReadKeePass)
+
+	require.Len(t, export.Entries, 3)
+
+	gmail := export.Entries[0]
+	require.Equal(t, "default", gmail.Group)
+	require.Equal(t, gohash_db.LoginInfo{
+		Name:        "gmail",
+		Username:    "joe@gmail.com",
+		Password:    "p4ss,word",
+		URL:         "https://mail.google.com",
+		Description: "personal mail",
+		CreatedAt:   time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2020, 6, 2, 9, 30, 0, 0, time.UTC),
+		OTP:         gmail.Info.OTP,
+	}, gmail.Info)
+	require.NotNil(t, gmail.Info.OTP)
+	require.Equal(t, "joe", gmail.Info.OTP.Account)
+
+	require.Equal(t, "Work", export.Entries[1].Group)
+	require.Equal(t, "jira-pass", export.Entries[1].Info.Password)
+	require.Equal(t, time.Date(2021, 3, 4, 10, 20, 30, 0, time.UTC), export.Entries[1].Info.UpdatedAt)
+
+	require.Equal(t, "Work/AWS", export.Entries[2].Group)
+	require.Equal(t, gohash_db.NoteKind, export.Entries[2].Info.Kind)
+	require.Equal(t, "1111\n2222", export.Entries[2].Info.Note)
+	require.True(t, export.Entries[2].Info.UpdatedAt.IsZero())
+
+	require.Equal(t, []Skipped{
+		{Item: "line 6", Reason: "it has no title"},
+		{Item: "line 7 (deleted)", Reason: "the recycle bin is not imported"},
+		{Item: "line 8", Reason: "expected 10 columns, found 2"},
+	}, export.Skipped)
+}
+
+func TestReadKeePassInvalidFiles(t *testing.T) {
+	_, err := ReadKeePassXML(strings.NewReader("<KeePassFile><Root>"))
+	require.Error(t, err)
+
+	_, err = ReadKeePassCSV(strings.NewReader("name,url,username,password\n"))
+	require.EqualError(t, err, "not a KeePassXC CSV export: missing the 'group' column")
+}
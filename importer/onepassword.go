@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+)
+
+// the categories of 1Password items that are not imported as logins.
+const (
+	onePasswordSecureNote = "003"
+	onePasswordPassword   = "005"
+)
+
+// onePasswordData the export.data file of a 1PUX export.
+type onePasswordData struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	CreatedAt    int64  `json:"createdAt"`
+	UpdatedAt    int64  `json:"updatedAt"`
+	State        string `json:"state"`
+	CategoryUUID string `json:"categoryUuid"`
+	Details      struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                     `json:"title"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+		PasswordHistory []struct {
+			Value string `json:"value"`
+			Time  int64  `json:"time"`
+		} `json:"passwordHistory"`
+	} `json:"details"`
+	Overview struct {
+		Title string   `json:"title"`
+		URL   string   `json:"url"`
+		Tags  []string `json:"tags"`
+	} `json:"overview"`
+}
+
+// ReadOnePassword reads a 1Password 1PUX export, or a 1Password CSV export, detecting the format from its contents.
+func ReadOnePassword(r io.Reader) (*Export, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return readOnePasswordPUX(data)
+	}
+	return ReadOnePasswordCSV(bytes.NewReader(data))
+}
+
+// ReadOnePasswordPUX reads a 1Password 1PUX export, which is a zip archive.
+//
+// Vaults are imported as top-level groups. Logins, passwords and secure notes are imported as login and note
+// entries, and other kinds of items as login entries. The fields of items are imported as custom fields,
+// except for one-time passwords, which are imported as such. Archived items are not imported.
+func ReadOnePasswordPUX(r io.Reader) (*Export, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return readOnePasswordPUX(data)
+}
+
+func readOnePasswordPUX(data []byte) (*Export, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a 1Password 1PUX export: %s", err.Error())
+	}
+	file, err := archive.Open("export.data")
+	if err != nil {
+		return nil, fmt.Errorf("not a 1Password 1PUX export: %s", err.Error())
+	}
+	defer file.Close()
+	var exportData onePasswordData
+	if err = json.NewDecoder(file).Decode(&exportData); err != nil {
+		return nil, fmt.Errorf("not a 1Password 1PUX export: %s", err.Error())
+	}
+	export := &Export{}
+	for _, account := range exportData.Accounts {
+		for _, vault := range account.Vaults {
+			group := groupPath([]string{vault.Attrs.Name})
+			for i := range vault.Items {
+				item := &vault.Items[i]
+				info, err := onePasswordInfo(item)
+				if err != nil {
+					title := item.Overview.Title
+					if title == "" {
+						title = "(untitled)"
+					}
+					export.Skipped = append(export.Skipped, Skipped{
+						Item: fmt.Sprintf("item '%s' of vault '%s'", title, vault.Attrs.Name), Reason: err.Error()})
+					continue
+				}
+				export.Entries = append(export.Entries, Entry{Group: group, Info: info})
+			}
+		}
+	}
+	return export, nil
+}
+
+func onePasswordInfo(item *onePasswordItem) (gohash_db.LoginInfo, error) {
+	info := gohash_db.LoginInfo{Name: entryName(item.Overview.Title, item.Overview.URL), URL: item.Overview.URL}
+	if item.State == "archived" {
+		return info, errors.New("archived items are not imported")
+	}
+	if info.Name == "" {
+		return info, errors.New("it has no title")
+	}
+	details := &item.Details
+	switch item.CategoryUUID {
+	case onePasswordSecureNote:
+		info.Kind, info.Note = gohash_db.NoteKind, details.NotesPlain
+	case onePasswordPassword:
+		info.Password, info.Description = details.Password, details.NotesPlain
+	default:
+		info.Description = details.NotesPlain
+	}
+	for _, field := range details.LoginFields {
+		switch field.Designation {
+		case "username":
+			info.Username = field.Value
+		case "password":
+			info.Password = field.Value
+		}
+	}
+	for _, section := range details.Sections {
+		for _, field := range section.Fields {
+			for kind, raw := range field.Value {
+				var value interface{}
+				if err := json.Unmarshal(raw, &value); err != nil {
+					continue
+				}
+				onePasswordField(&info, field.Title, kind, value)
+			}
+		}
+	}
+	for _, record := range details.PasswordHistory {
+		if len(info.PasswordHistory) < gohash_db.MaxPasswordHistory {
+			info.PasswordHistory = append(info.PasswordHistory, gohash_db.PasswordRecord{
+				Password: record.Value, ReplacedAt: unixTime(record.Time)})
+		}
+	}
+	addTags(&info, item.Overview.Tags)
+	info.CreatedAt = unixTime(item.CreatedAt)
+	info.UpdatedAt = unixTime(item.UpdatedAt)
+	return info, nil
+}
+
+// onePasswordField adds a field of a 1Password item, whose value has the given kind, to an entry.
+func onePasswordField(info *gohash_db.LoginInfo, title, kind string, value interface{}) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = fmt.Sprint(v)
+	case map[string]interface{}:
+		// email fields are given as objects in recent exports
+		text, _ = v["email_address"].(string)
+	}
+	if text == "" {
+		return
+	}
+	if kind == "totp" && info.OTP == nil {
+		if otp, err := gohash_db.ParseOTP(text); err == nil {
+			info.OTP = otp
+			return
+		}
+	}
+	if title == "" {
+		title = kind
+	}
+	secret := kind == "concealed" || kind == "totp" || kind == "creditCardNumber"
+	info.Fields = append(info.Fields, gohash_db.CustomField{Name: title, Value: text, Secret: secret})
+}
+
+func unixTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
+
+// onePasswordColumns the names that each column of a 1Password CSV export may have, in lower case.
+var onePasswordColumns = map[string][]string{
+	"title":    {"title", "name"},
+	"url":      {"url", "website", "login_url"},
+	"username": {"username", "login_username"},
+	"password": {"password", "login_password"},
+	"notes":    {"notes", "notesplain"},
+	"otp":      {"otpauth", "one-time password"},
+	"tags":     {"tags"},
+	"archived": {"archived"},
+}
+
+// ReadOnePasswordCSV reads a 1Password CSV export, whose columns are given in its header.
+//
+// CSV exports do not include vaults, so all entries are imported into the default group. Archived items are
+// not imported.
+func ReadOnePasswordCSV(r io.Reader) (*Export, error) {
+	export := &Export{}
+	err := readCSV(r, "1Password", onePasswordColumns, []string{"title", "password"}, export, func(row csvRow) {
+		value := row.values
+		if strings.EqualFold(value["archived"], "true") {
+			export.Skipped = append(export.Skipped, Skipped{Item: fmt.Sprintf("%s (%s)", row.item(), value["title"]),
+				Reason: "archived items are not imported"})
+			return
+		}
+		info, err := newEntry(value["title"], value["username"], value["password"], value["url"],
+			value["notes"], value["otp"])
+		if err != nil {
+			export.Skipped = append(export.Skipped, Skipped{Item: row.item(), Reason: err.Error()})
+			return
+		}
+		addTags(&info, strings.Split(value["tags"], ","))
+		export.Entries = append(export.Entries, Entry{Group: gohash_db.DefaultGroup, Info: info})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"github.com/stretchr/testify/require"
+)
+
+// onePasswordPUX creates a 1PUX export with the contents of the testdata/onepassword directory.
+func onePasswordPUX(t *testing.T) []byte {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	data, err := ioutil.ReadFile("testdata/onepassword/export.data")
+	require.NoError(t, err)
+	file, err := archive.Create("export.data")
+	require.NoError(t, err)
+	_, err = file.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	return buffer.Bytes()
+}
+
+func TestReadOnePasswordPUX(t *testing.T) {
+	export, err := ReadOnePassword(bytes.NewReader(onePasswordPUX(t)))
+	require.NoError(t, err)
+
+	require.Len(t, export.Entries, 3)
+
+	gmail := export.Entries[0]
+	require.Equal(t, "Private", gmail.Group)
+	require.Equal(t, "Gmail", gmail.Info.Name)
+	require.Equal(t, gohash_db.LoginKind, gmail.Info.Kind)
+	require.Equal(t, "joe@example.com", gmail.Info.Username)
+	require.Equal(t, "gmail-pass", gmail.Info.Password)
+	require.Equal(t, "https://mail.google.com", gmail.Info.URL)
+	require.Equal(t, "personal mail", gmail.Info.Description)
+	require.Equal(t, []string{"mail", "personal-stuff"}, gmail.Info.Tags)
+	require.Equal(t, []gohash_db.CustomField{
+		{Name: "recovery email", Value: "backup@example.com"},
+		{Name: "PIN", Value: "9876", Secret: true},
+	}, gmail.Info.Fields)
+	require.NotNil(t, gmail.Info.OTP)
+	require.Equal(t, "Google", gmail.Info.OTP.Issuer)
+	require.Equal(t, []gohash_db.PasswordRecord{{Password: "old-gmail-pass", ReplacedAt: time.Unix(1600000000, 0).UTC()}},
+		gmail.Info.PasswordHistory)
+	require.Equal(t, time.Unix(1614298956, 0).UTC(), gmail.Info.CreatedAt)
+	require.Equal(t, time.Unix(1635346445, 0).UTC(), gmail.Info.UpdatedAt)
+
+	codes := export.Entries[1]
+	require.Equal(t, "Private", codes.Group)
+	require.Equal(t, gohash_db.NoteKind, codes.Info.Kind)
+	require.Equal(t, "1111\n2222", codes.Info.Note)
+
+	vpn := export.Entries[2]
+	require.Equal(t, "Work- Shared", vpn.Group)
+	require.Equal(t, "vpn", vpn.Info.Name)
+	require.Equal(t, "vpn-pass", vpn.Info.Password)
+
+	require.Equal(t, []Skipped{
+		{Item: "item 'old jira' of vault 'Work: Shared'", Reason: "archived items are not imported"},
+	}, export.Skipped)
+}
+
+func TestReadOnePasswordCSV(t *testing.T) {
+	export := readFixture(t, "onepassword.csv", ReadOnePassword)
+
+	require.Len(t, export.Entries, 2)
+
+	gmail := export.Entries[0]
+	require.Equal(t, "default", gmail.Group)
+	require.Equal(t, "Gmail", gmail.Info.Name)
+	require.Equal(t, "joe@example.com", gmail.Info.Username)
+	require.Equal(t, "gmail-pass", gmail.Info.Password)
+	require.Equal(t, "personal mail", gmail.Info.Description)
+	require.Equal(t, []string{"mail", "personal"}, gmail.Info.Tags)
+	require.NotNil(t, gmail.Info.OTP)
+
+	require.Equal(t, "github.com", export.Entries[1].Info.Name)
+
+	require.Equal(t, []Skipped{
+		{Item: "line 4 (old jira)", Reason: "archived items are not imported"},
+		{Item: "line 5", Reason: "it has no title"},
+	}, export.Skipped)
+}
+
+func TestReadInvalidOnePasswordPUX(t *testing.T) {
+	_, err := ReadOnePassword(strings.NewReader("PK\x03\x04 not really a zip file"))
+	require.Error(t, err)
+}
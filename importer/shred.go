@@ -0,0 +1,43 @@
+package importer
+
+import (
+	"os"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+)
+
+// Shred overwrites a file with random bytes, then removes it, so that the contents of an export file can't be
+// recovered from the disk.
+//
+// Some file systems and disks, such as copy-on-write file systems and SSDs, may still keep copies of the
+// original contents, which only full-disk encryption protects.
+func Shred(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	const chunkSize = 64 * 1024
+	for remaining := info.Size(); remaining > 0; remaining -= chunkSize {
+		size := remaining
+		if size > chunkSize {
+			size = chunkSize
+		}
+		if _, err = file.Write(encryption.GenerateRandomBytes(uint32(size))); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
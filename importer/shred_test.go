@@ -0,0 +1,32 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShred(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-hash-shred")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "passwords.csv")
+	contents := make([]byte, 100*1024)
+	require.NoError(t, ioutil.WriteFile(path, contents, 0600))
+	// a hard link to the file shows what was written to it
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Link(path, link))
+
+	require.NoError(t, Shred(path))
+
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+	overwritten, err := ioutil.ReadFile(link)
+	require.NoError(t, err)
+	require.Len(t, overwritten, len(contents))
+	require.NotEqual(t, contents, overwritten)
+	require.Error(t, Shred(path))
+}
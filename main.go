@@ -11,12 +11,14 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/chzyer/readline"
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/renatoathaydes/go-hash/encryption"
 	"github.com/renatoathaydes/go-hash/gohash_db"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -36,7 +38,7 @@ func init() {
 	}
 
 	// and restore it on exit
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill)
 	go func() {
 		<-c
@@ -73,7 +75,9 @@ func isDir(path string) bool {
 	return false
 }
 
-func createPassword() string {
+// createPassword asks the user for a new master password.
+// The caller should zero out the returned password once it's been used.
+func createPassword() []byte {
 	for i := 0; i < 10; i++ {
 		print("Please enter a master password: ")
 		pass, err := terminal.ReadPassword(int(syscall.Stdin))
@@ -93,18 +97,21 @@ func createPassword() string {
 					break
 				}
 				if bytes.Equal(pass, pass2) {
-					return string(pass)
+					encryption.Zero(pass2)
+					return pass
 				}
+				encryption.Zero(pass2)
 				println("No match! Try again or just hit Enter to start again.")
 			}
 		} else {
 			println("Password too short! Please use at least 8 characters")
 		}
+		encryption.Zero(pass)
 	}
 	panic("Too many attempts!")
 }
 
-func openDatabase(dbFilePath string) (state State, userPass string) {
+func openDatabase(dbFilePath string) (state State, key *gohash_db.SessionKey) {
 	for i := 0; i < 5; i++ {
 		print("Please enter your master password: ")
 		bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
@@ -112,8 +119,8 @@ func openDatabase(dbFilePath string) (state State, userPass string) {
 		if err != nil {
 			panic(err)
 		}
-		userPass = string(bytePassword)
-		state, err = gohash_db.ReadDatabase(dbFilePath, userPass)
+		state, key, err = gohash_db.OpenDatabase(dbFilePath, bytePassword)
+		encryption.Zero(bytePassword)
 		if err != nil {
 			println("✗ Error: " + err.Error())
 		} else {
@@ -125,6 +132,25 @@ func openDatabase(dbFilePath string) (state State, userPass string) {
 	panic("Aborting. Too many attempts!")
 }
 
+// verifyPassword asks the user for the master password until it matches the session key.
+func verifyPassword(key *gohash_db.SessionKey) {
+	for i := 0; i < 5; i++ {
+		print("Please enter your master password: ")
+		bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
+		println("")
+		if err != nil {
+			panic(err)
+		}
+		ok := key.VerifyPassword(bytePassword)
+		encryption.Zero(bytePassword)
+		if ok {
+			return
+		}
+		println("✗ Error: incorrect password")
+	}
+	panic("Aborting. Too many attempts!")
+}
+
 func splitTrimN(text string, max int) []string {
 	result := make([]string, max)
 	parts := strings.SplitN(text, " ", max)
@@ -134,20 +160,219 @@ func splitTrimN(text string, max int) []string {
 	return result
 }
 
-func runCliLoop(state *State, dbPath string, userPass string, passwordTimeout *time.Duration) {
-	grBox := stringBox{value: "default"}
-	mpBox := stringBox{value: userPass}
-	userPass = ""
-	reader := bufio.NewReader(os.Stdin)
+// cliOptions the options given to go-hash in the command-line.
+type cliOptions struct {
+	dbFilePath      string
+	passwordTimeout *time.Duration
+	backups         int
+	trashRetention  time.Duration
+	autosave        bool
+	readOnly        bool
+	output          outputOptions
+	// passwordFd the file descriptor to read the master password from, or -1 to use other sources.
+	passwordFd int
+	// subcommand the subcommand to run, followed by its arguments, if go-hash is not run interactively.
+	subcommand []string
+}
+
+// acquireLock acquires the lock of the database, so that other go-hash processes cannot write to it.
+// If the lock cannot be acquired, the user may choose to open the database in read-only mode, in which
+// case nil is returned.
+func acquireLock(opts *cliOptions, reader *bufio.Reader) *gohash_db.FileLock {
+	if opts.readOnly {
+		return nil
+	}
+	lock, err := gohash_db.AcquireLock(opts.dbFilePath)
+	if err == nil {
+		return lock
+	}
+	if lockErr, ok := err.(*gohash_db.LockedError); ok {
+		fmt.Printf("⚠ Warning: %s.\n", lockErr.Error())
+		println("Saving changes now could overwrite the changes made by the other process.")
+	} else {
+		fmt.Printf("⚠ Warning: unable to lock the database (%s).\n", err.Error())
+	}
+	if yesNoQuestion("Do you want to open the database in read-only mode?", reader, true) {
+		opts.readOnly = true
+		return nil
+	}
+	if _, ok := err.(*gohash_db.LockedError); ok {
+		fmt.Printf("If you are sure no other go-hash process is using the database, delete the lock file: %s\n",
+			gohash_db.LockPath(opts.dbFilePath))
+	}
+	os.Exit(1)
+	return nil
+}
+
+// mergeExternalChanges merges the changes saved to the database file by another process (e.g. another device
+// syncing the file through a shared folder) since the file was last read or written by this session into state.
+// The user is asked which version to keep for entries changed both in this session and in the file.
+//
+// Returns whether any external changes were merged, and false if the database file cannot be read,
+// in which case it should not be overwritten.
+func mergeExternalChanges(dbPath string, stamp gohash_db.FileStamp, base State, state *State,
+	keyBox *sessionKeyBox, reader *bufio.Reader) (merged bool, ok bool) {
+	changed, err := stamp.HasChanged(dbPath)
+	if err != nil {
+		fmt.Printf("Error: unable to check whether the database file was modified (%s).\n", err.Error())
+		return false, false
+	}
+	if !changed {
+		return false, true
+	}
+
+	println("⚠ The database file was modified by another process since it was last read or saved.")
+	remote, err := gohash_db.ReadDatabaseWithKey(dbPath, keyBox.key)
+	if err == gohash_db.ErrKeyMismatch {
+		println("The database file was saved with a different master password or KDF parameters.")
+		print("Please enter the master password it was saved with: ")
+		pass, err2 := terminal.ReadPassword(int(syscall.Stdin))
+		println("")
+		if err2 != nil {
+			panic(err2)
+		}
+		remote, err = readDatabaseWithPassword(dbPath, pass)
+		encryption.Zero(pass)
+		if err == nil {
+			println("Warning: the database will be saved with the master password and KDF parameters of this session.")
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: unable to read the modified database file (%s).\n", err.Error())
+		return false, false
+	}
+
+	result := gohash_db.Merge(base, *state, remote, func(conflict gohash_db.Conflict) *LoginInfo {
+		return resolveConflict(conflict, reader)
+	})
+	*state = result.State
+	fmt.Printf("✔ Merged %d change(s) from the database file, %d conflict(s) resolved.\n",
+		result.RemoteChanges, result.Conflicts)
+	return result.RemoteChanges+result.Conflicts > 0, true
+}
+
+// resolveConflict asks the user which version of a conflicting entry to keep.
+func resolveConflict(conflict gohash_db.Conflict, reader *bufio.Reader) *LoginInfo {
+	fmt.Printf("\nThe entry '%s' in group '%s' was changed both in this session and in the database file.\n",
+		conflict.Name, conflict.Group)
+	for _, version := range []struct {
+		name  string
+		entry *LoginInfo
+	}{{"Local version (this session)", conflict.Local}, {"Remote version (database file)", conflict.Remote}} {
+		println(version.name + ":")
+		if version.entry == nil {
+			println("  (removed)")
+		} else {
+			println(version.entry.String())
+		}
+	}
+	if conflict.Local != nil && conflict.Remote != nil && conflict.Local.Password != conflict.Remote.Password {
+		println("The passwords of the two versions are different.")
+	}
+	newest := conflict.Newest()
+	keepLocal := ask2OptionsQuestion("Which version do you want to keep, local or remote?", reader,
+		"l", "r", newest == conflict.Local)
+	if keepLocal {
+		return conflict.Local
+	}
+	return conflict.Remote
+}
+
+func runCliLoop(state *State, key *gohash_db.SessionKey, stamp gohash_db.FileStamp,
+	reader *bufio.Reader, cliOpts cliOptions) {
+	dbPath := cliOpts.dbFilePath
+	passwordTimeout := cliOpts.passwordTimeout
+	grBox := stringBox{value: gohash_db.DefaultGroup}
+	keyBox := sessionKeyBox{key: key}
+	defer keyBox.replace(nil)
+	autosave := cliOpts.autosave && !cliOpts.readOnly
+	dirty := false       // whether there are unsaved changes
+	base := state.Copy() // the state as last read or saved, used to merge external changes
+	var recorded State   // the state after the last change, recorded as the state before the next change
+	var history gohash_db.UndoHistory
+	var afterSave func() // set by commands that must do something after their changes are saved
 	prompt := func() string {
 		var modifier string
-		if len(grBox.value) > 0 && grBox.value != "default" {
-			modifier = ":" + grBox.value
+		if cliOpts.readOnly {
+			modifier = "[read-only]"
+		}
+		if len(grBox.value) > 0 && grBox.value != gohash_db.DefaultGroup {
+			modifier += ":" + grBox.value
+		}
+		if dirty {
+			modifier += "*"
 		}
 		return fmt.Sprintf("\033[31mgo-hash%s»\033[0m ", modifier)
 	}
 
-	commands := createCommands(state, &grBox, &mpBox)
+	save := func() bool {
+		if cliOpts.readOnly {
+			println("Error: the database was opened in read-only mode, changes cannot be saved.")
+			return false
+		}
+		merged, ok := mergeExternalChanges(dbPath, stamp, base, state, &keyBox, reader)
+		if !ok {
+			println("Error: the database was not saved.")
+			return false
+		}
+		if merged {
+			recorded = state.Copy()
+		}
+		if merged && history.NextUndo() != nil {
+			// the recorded states don't include the external changes, which would be lost on undo
+			history.Clear()
+			println("Warning: the undo history was cleared, as changes from the database file were merged.")
+		}
+		err := gohash_db.WriteDatabaseWithKey(dbPath, keyBox.key, cliOpts.backups, state)
+		if err != nil {
+			println("Error writing to database: " + err.Error())
+			return false
+		}
+		dirty = false
+		base = state.Copy()
+		if stamp, err = gohash_db.StampFile(dbPath); err != nil {
+			println("Warning: unable to read the saved database file: " + err.Error())
+		}
+		return true
+	}
+
+	// canQuit checks whether there are unsaved changes before quitting
+	canQuit := func() bool {
+		if dirty && cliOpts.readOnly {
+			return yesNoQuestion("There are unsaved changes, which cannot be saved in read-only mode. "+
+				"Do you want to quit anyway?", reader, false)
+		}
+		if dirty && yesNoQuestion("There are unsaved changes. Do you want to save them before quitting?", reader, true) {
+			return save()
+		}
+		return true
+	}
+
+	commands := createCommands(state, &grBox, &keyBox, dbPath, cliOpts.output, cliOpts.readOnly)
+	commands["save"] = saveCommand{autosave: &autosave, save: save}
+	commands["undo"] = undoCommand{history: &history, groupBox: &grBox}
+	commands["redo"] = undoCommand{history: &history, groupBox: &grBox, redo: true}
+	commands["import"] = importCommand{afterSave: &afterSave, save: func() bool {
+		return !dirty || save()
+	}}
+	commands["trash"] = trashCommand{retention: cliOpts.trashRetention, items: func() []string {
+		items := make([]string, len(state.Trash()))
+		for i := range items {
+			items[i] = strconv.Itoa(i + 1)
+		}
+		return items
+	}}
+
+	if cliOpts.trashRetention > 0 && !cliOpts.readOnly {
+		if purged := state.PurgeTrash(time.Now().Add(-cliOpts.trashRetention)); purged > 0 {
+			fmt.Printf("Permanently deleted the entries (%d) that were in the trash for more than %s.\n",
+				purged, retentionDescription(cliOpts.trashRetention))
+			dirty = true
+			if autosave {
+				save()
+			}
+		}
+	}
 
 	cli, err := readline.NewEx(&readline.Config{
 		Prompt:          prompt(),
@@ -159,6 +384,7 @@ func runCliLoop(state *State, dbPath string, userPass string, passwordTimeout *t
 	}
 	defer cli.Close()
 
+	recorded = state.Copy()
 	eofCount := 0
 	idleSince := time.Now()
 
@@ -169,7 +395,7 @@ Loop:
 		if err != nil {
 			switch err {
 			case readline.ErrInterrupt:
-				if len(line) == 0 {
+				if len(line) == 0 && canQuit() {
 					println("Warning: Received interrupt, exiting.")
 					break Loop
 				}
@@ -195,11 +421,14 @@ Loop:
 
 		switch cmd {
 		case "quit":
-			break Loop
+			if canQuit() {
+				break Loop
+			}
 		case "exit":
-			if grBox.value != "default" {
-				grBox.value = "default"
-			} else {
+			if grBox.value != gohash_db.DefaultGroup {
+				// go up to the parent group
+				grBox.value = gohash_db.ParentGroup(grBox.value)
+			} else if canQuit() {
 				break Loop
 			}
 		default:
@@ -207,25 +436,31 @@ Loop:
 			if command != nil {
 				if command.requiresPasswordIfIdleTooLong() {
 					if passwordTimeout != nil && totalIdleTime > *passwordTimeout {
-						mpBox.value = ""
-
 						// prompt for password before allowing command to run
 						fmt.Printf("⚠ password required (idle %s)\n", totalIdleTime.Round(time.Second))
 
-						// if trying to re-open the database fails, the process will exit, otherwise just continue
-						*state, userPass = openDatabase(dbPath)
-						mpBox.value = userPass
-						userPass = ""
+						// if the user fails to enter the password, the process will exit, otherwise just continue
+						verifyPassword(keyBox.key)
 					}
 
 					// reset the idle timer only on commands that are sensitive
 					idleSince = time.Now()
 				}
 
-				command.run(state, grBox.value, args, reader)
-				err = gohash_db.WriteDatabase(dbPath, mpBox.value, state)
-				if err != nil {
-					println("Error writing to database: " + err.Error())
+				if command.run(state, grBox.value, args, reader) {
+					dirty = true
+					if _, isUndo := command.(undoCommand); !isUndo {
+						history.Record(strings.TrimSpace(line), recorded, *state)
+					}
+					// only changes need a copy of the state, most commands just read it
+					recorded = state.Copy()
+				}
+				if dirty && autosave {
+					save()
+				}
+				if afterSave != nil {
+					afterSave()
+					afterSave = nil
 				}
 			} else if len(cmd) > 0 {
 				fmt.Printf("Unknown command: '%s'. Type 'help' for usage.\n", cmd)
@@ -234,29 +469,69 @@ Loop:
 	}
 }
 
-func parseOptions() (dbFilePath string, passwordTimeout *time.Duration) {
+func parseOptions() (opts cliOptions) {
 	var defaultPasswordTimeout = time.Duration(120) * time.Second
+	opts.passwordTimeout = &defaultPasswordTimeout
+	opts.backups = gohash_db.DefaultBackups
+	opts.trashRetention = gohash_db.DefaultTrashRetention
+	opts.autosave = true
+	opts.passwordFd = -1
+
 	if len(os.Args) == 1 { // no args given
-		return getGoHashFilePath(), &defaultPasswordTimeout
+		opts.dbFilePath = getGoHashFilePath()
+		return
 	}
-	if len(os.Args) == 2 && !strings.HasPrefix(os.Args[1], "-") { // one arg, no flag
-		return os.Args[1], &defaultPasswordTimeout
+	if len(os.Args) == 2 && !strings.HasPrefix(os.Args[1], "-") && subcommands()[os.Args[1]] == nil { // one arg, no flag
+		opts.dbFilePath = os.Args[1]
+		return
 	}
 
-	var idleSec uint // password required after inactivity
+	var idleSec uint     // password required after inactivity
+	var backupsFlag uint // number of backups to keep
+	var trashDays uint   // days deleted entries are kept in the trash
 
 	flag.UintVar(&idleSec, "idle", 120, "password timeout, in seconds (use 0 for no timeout)")
-	flag.StringVar(&dbFilePath, "db", getGoHashFilePath(), "database file")
+	flag.StringVar(&opts.dbFilePath, "db", getGoHashFilePath(), "database file")
+	flag.UintVar(&backupsFlag, "backups", gohash_db.DefaultBackups, "number of backups of the database file to keep")
+	flag.UintVar(&trashDays, "trash", uint(gohash_db.DefaultTrashRetention/(24*time.Hour)),
+		"number of days deleted entries are kept in the trash (use 0 to keep them until the trash is emptied)")
+	flag.BoolVar(&opts.autosave, "autosave", true, "save the database after every change (use 'save' otherwise)")
+	flag.BoolVar(&opts.readOnly, "readonly", false, "open the database in read-only mode, without locking it")
+	formatFlag := flag.String("format", "table", "format used to list and show groups and entries: table, json or yaml")
+	flag.BoolVar(&opts.output.withSecrets, "with-secrets", false,
+		"include passwords and other secrets when showing entries in the json or yaml formats")
+	flag.IntVar(&opts.passwordFd, "password-fd", -1, "file descriptor to read the master password from (subcommands only)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-db <database filename>] [-idle <password timeout>] "+
+			"[-backups <number of backups>] [-trash <days>] [-autosave=false] [-readonly] [-format <format>] "+
+			"[-with-secrets] [-password-fd <fd>] "+
+			"[<subcommand> <args>]\n\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), subcommandsUsage)
+	}
 	flag.Parse()
 
+	var err error
+	if opts.output.format, err = gohash_db.ParseOutputFormat(*formatFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s.\n", err.Error())
+		os.Exit(exitUsage)
+	}
+
 	if len(flag.Args()) > 0 {
-		fmt.Printf("usage: %s [-db <database filename>] [-idle <password timeout>]", os.Args[0])
-		os.Exit(2)
+		if subcommands()[flag.Arg(0)] == nil {
+			fmt.Fprintf(os.Stderr, "Error: unknown subcommand: '%s'.\n", flag.Arg(0))
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+		opts.subcommand = flag.Args()
 	}
 
 	timeout := time.Duration(idleSec) * time.Second
-	passwordTimeout = &timeout
+	opts.passwordTimeout = &timeout
+	opts.backups = int(backupsFlag)
+	opts.trashRetention = time.Duration(trashDays) * 24 * time.Hour
 
+	dbFilePath := opts.dbFilePath
 	if !parentDirExists(dbFilePath) {
 		panic("The provided file is under a non-existing directory. Please create the directory manually first.")
 	}
@@ -267,21 +542,44 @@ func parseOptions() (dbFilePath string, passwordTimeout *time.Duration) {
 }
 
 func main() {
-	var userPass string
+	var key *gohash_db.SessionKey
 	var state State
+	var stamp gohash_db.FileStamp
+	opts := parseOptions()
+	if opts.subcommand != nil {
+		os.Exit(runSubcommand(opts))
+	}
+
 	println("Go-Hash version " + gohash_db.DBVersion)
 	println("")
 
-	dbFilePath, passwordTimeout := parseOptions()
+	dbFilePath := opts.dbFilePath
+	newDatabase := false
+	reader := bufio.NewReader(os.Stdin)
+
+	lock := acquireLock(&opts, reader)
+	if lock != nil {
+		defer lock.Release()
+	}
 
 	dbFile, err := os.Open(dbFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			if opts.readOnly {
+				println("Error: no database exists yet, and it cannot be created in read-only mode.")
+				os.Exit(1)
+			}
 			println("No database exists yet, to create one, you need to provide a strong password first.")
 			println("A strong password could be a phrase you could remember easily but that is hard to guess.")
 			println("To make it harder to guess, include both upper and lower-case letters, numbers and special characters like ? and @.")
 			println("If you forget this password, there's no way to recover it or your data, so be careful!\n")
-			userPass = createPassword()
+			userPass := createPassword()
+			key, err = gohash_db.NewSessionKey(userPass, encryption.DefaultKDFParams())
+			encryption.Zero(userPass)
+			if err != nil {
+				panic(err)
+			}
+			newDatabase = true
 		} else {
 			panic(err)
 		}
@@ -289,13 +587,28 @@ func main() {
 	} else {
 		// the DB exists, check if the user can open it
 		dbFile.Close()
-		state, userPass = openDatabase(dbFilePath)
+		// the file is stamped before being read, so that any changes made after that are detected later
+		if stamp, err = gohash_db.StampFile(dbFilePath); err != nil {
+			panic(err)
+		}
+		state, key = openDatabase(dbFilePath)
 	}
 
 	if len(state) == 0 {
-		state["default"] = []LoginInfo{}
+		state[gohash_db.DefaultGroup] = []LoginInfo{}
+	}
+
+	if newDatabase {
+		err = gohash_db.WriteDatabaseWithKey(dbFilePath, key, opts.backups, &state)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("\n✔ Created database at %s\n", dbFilePath)
+		if stamp, err = gohash_db.StampFile(dbFilePath); err != nil {
+			panic(err)
+		}
 	}
 
 	println("\nWelcome, go-hash at your service.\n")
-	runCliLoop(&state, dbFilePath, userPass, passwordTimeout)
+	runCliLoop(&state, key, stamp, reader, opts)
 }
@@ -1,7 +1,9 @@
 package mobileapi
 
 import (
+	"errors"
 	"sort"
+	"time"
 
 	"github.com/renatoathaydes/go-hash/gohash_db"
 )
@@ -30,6 +32,8 @@ type Entry struct {
 type Database struct {
 	FileName string
 	state    gohash_db.State
+	// key the session key the database was opened with, used to write it. Nil once the database is closed.
+	key *gohash_db.SessionKey
 }
 
 // Next returns the next EntryIterator, if any, or nil if none is available.
@@ -53,6 +57,11 @@ func (iter *EntryIterator) Next() *Entry {
 	return nil
 }
 
+// ID the unique and immutable ID of this Entry.
+func (entry *Entry) ID() string {
+	return entry.loginInfo.ID
+}
+
 // Name of this Entry.
 func (entry *Entry) Name() string {
 	return entry.loginInfo.Name
@@ -78,6 +87,12 @@ func (entry *Entry) Url() string {
 	return entry.loginInfo.URL
 }
 
+// CreatedAt the instance at which this entry was created.
+// As epoch-seconds since 1970-01-01T00:00:00.
+func (entry *Entry) CreatedAt() int64 {
+	return entry.loginInfo.CreatedAt.Unix()
+}
+
 // UpdatedAt the instance at which this entry was last updated.
 // As epoch-milliseconds since 1970-01-01T00:00:00.
 func (entry *Entry) UpdatedAt() int64 {
@@ -86,21 +101,65 @@ func (entry *Entry) UpdatedAt() int64 {
 
 // Iter returns a GroupIterator which can be used to iterate over the groups in this database.
 func (db *Database) Iter() *GroupIterator {
-	keys := make([]string, len(db.state))
-	i := 0
+	keys := make([]string, 0, len(db.state))
 	for key := range db.state {
-		keys[i] = key
-		i++
+		if key != gohash_db.TrashGroup {
+			keys = append(keys, key)
+		}
 	}
 	sort.Strings(keys)
 	return &GroupIterator{keys: keys, state: db.state}
 }
 
-// ReadDatabase reads a go-hash database.
+// MoveEntry moves the entry called name, in the given group, to the target group.
+// The entry keeps its ID and timestamps. Use Write to save the change.
+//
+// An error is returned if the target group already has an entry with the same name.
+func (db *Database) MoveEntry(group, name, targetGroup string) error {
+	index, ok := db.state.FindEntry(group, name)
+	if !ok {
+		return gohash_db.ErrEntryNotFound
+	}
+	_, err := db.state.MoveEntry(gohash_db.EntryRef{Group: group, Index: index}, targetGroup, "")
+	return err
+}
+
+// CopyEntry adds a copy of the entry called name, in the given group, to the target group, which may be
+// the same group. If the target group already has an entry with the same name, the copy is given a unique name.
+//
+// Returns the name of the copy. Use Write to save the change.
+func (db *Database) CopyEntry(group, name, targetGroup string) (string, error) {
+	index, ok := db.state.FindEntry(group, name)
+	if !ok {
+		return "", gohash_db.ErrEntryNotFound
+	}
+	newName := db.state.UniqueEntryName(targetGroup, name)
+	_, err := db.state.CopyEntry(gohash_db.EntryRef{Group: group, Index: index}, targetGroup, newName, time.Now())
+	return newName, err
+}
+
+// Write writes the database to its file, keeping the default number of backups, without asking for the
+// master password again.
+func (db *Database) Write() error {
+	if db.key == nil {
+		return errors.New("the database was closed")
+	}
+	return gohash_db.WriteDatabaseWithKey(db.FileName, db.key, gohash_db.DefaultBackups, &db.state)
+}
+
+// Close destroys the key used to write the database. The database can still be read, but not written.
+func (db *Database) Close() {
+	if db.key != nil {
+		db.key.Destroy()
+		db.key = nil
+	}
+}
+
+// ReadDatabase reads a go-hash database, which can be written again with Write until it's closed.
 func ReadDatabase(filePath, password string) (*Database, error) {
-	state, err := gohash_db.ReadDatabase(filePath, password)
+	state, key, err := gohash_db.OpenDatabase(filePath, []byte(password))
 	if err != nil {
 		return nil, err
 	}
-	return &Database{FileName: filePath, state: state}, nil
+	return &Database{FileName: filePath, state: state, key: key}, nil
 }
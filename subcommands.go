@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/renatoathaydes/go-hash/encryption"
+	"github.com/renatoathaydes/go-hash/gohash_db"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Exit status codes of go-hash subcommands.
+const (
+	exitOK = 0
+	// exitError any error not covered by other codes.
+	exitError = 1
+	// exitUsage invalid command-line arguments.
+	exitUsage = 2
+	// exitAuth the database could not be opened, e.g. the master password is missing or incorrect.
+	exitAuth = 3
+	// exitNotFound the entry, group or field does not exist.
+	exitNotFound = 4
+	// exitLocked the database is in use by another go-hash process.
+	exitLocked = 5
+)
+
+// passwordFileEnv the environment variable holding the path of a file containing the master password.
+const passwordFileEnv = "GO_HASH_PASSWORD_FILE"
+
+// subcommand a command that runs once, outside of the go-hash prompt, so that go-hash can be used from scripts,
+// e.g. 'go-hash get work:aws -f password'.
+//
+// Subcommands write the information they're asked for to stdout, and any messages and errors to stderr.
+type subcommand interface {
+	// parse parses the arguments of the subcommand, returning whether it changes the database.
+	parse(args []string) (writes bool, err error)
+	run(state *State, reader *bufio.Reader, output outputOptions) (changed bool, status int)
+}
+
+// subcommands creates the subcommands by name.
+func subcommands() map[string]subcommand {
+	return map[string]subcommand{
+		"get": &getSubcommand{},
+		"ls":  &lsSubcommand{},
+		"add": &addSubcommand{},
+		"rm":  &rmSubcommand{},
+	}
+}
+
+const subcommandsUsage = `
+Subcommands:
+  get [<group>:]<name> | #<id> [-f <field>]
+      prints an entry (in the format given by -format) or, with -f, one of its fields: password, username,
+      url, description, note, otp, id, or the name of a custom field.
+  ls [<group>]
+      lists the sub-groups (ending with '/') and entries of a group, or of the top-level group, or prints
+      the listing of the group in the format given by -format.
+  add [<group>:]<name> [-u <username>] [-url <URL>] [-d <description>] [-g]
+      adds an entry, reading its password from the first line of stdin, or generating it with -g.
+  rm [<group>:]<name> | #<id>
+      moves an entry to the trash.
+
+The master password is read from the file descriptor given by the -password-fd flag, from the file
+whose path is given by the ` + passwordFileEnv + ` environment variable or, otherwise, from the terminal.
+
+Exit status: 0 on success, 1 on errors, 2 on invalid usage, 3 if the database can't be opened
+(e.g. incorrect master password), 4 if the entry, group or field does not exist, 5 if the database is locked.
+`
+
+// runSubcommand runs a subcommand given in the command-line, returning the exit status of go-hash.
+func runSubcommand(opts cliOptions) int {
+	name, args := opts.subcommand[0], opts.subcommand[1:]
+	cmd := subcommands()[name]
+	writes, err := cmd.parse(args)
+	if err != nil {
+		return subcommandError(exitUsage, "%s. Type 'go-hash -h' for usage", err.Error())
+	}
+	if writes && opts.readOnly {
+		return subcommandError(exitUsage, "the '%s' subcommand cannot run in read-only mode", name)
+	}
+	if writes {
+		lock, err := gohash_db.AcquireLock(opts.dbFilePath)
+		if _, ok := err.(*gohash_db.LockedError); ok {
+			return subcommandError(exitLocked, "%s", err.Error())
+		} else if err != nil {
+			return subcommandError(exitError, "unable to lock the database (%s)", err.Error())
+		}
+		defer lock.Release()
+	}
+	if _, err := os.Stat(opts.dbFilePath); err != nil {
+		return subcommandError(exitError, "unable to open the database (%s)", err.Error())
+	}
+
+	pass, err := readMasterPassword(opts.passwordFd)
+	if err != nil {
+		return subcommandError(exitAuth, "%s", err.Error())
+	}
+	state, key, err := gohash_db.OpenDatabase(opts.dbFilePath, pass)
+	encryption.Zero(pass)
+	if err != nil {
+		return subcommandError(exitAuth, "%s", err.Error())
+	}
+	defer key.Destroy()
+
+	changed, status := cmd.run(&state, bufio.NewReader(os.Stdin), opts.output)
+	if changed {
+		if err = gohash_db.WriteDatabaseWithKey(opts.dbFilePath, key, opts.backups, &state); err != nil {
+			return subcommandError(exitError, "unable to write to the database (%s)", err.Error())
+		}
+	}
+	return status
+}
+
+func subcommandError(status int, format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "Error: "+format+".\n", args...)
+	return status
+}
+
+// readMasterPassword reads the master password from the file descriptor fd, if it's not negative, from the file
+// given by the passwordFileEnv environment variable, if set, or else, from the terminal.
+// The caller should zero out the returned password once it's been used.
+func readMasterPassword(fd int) ([]byte, error) {
+	if fd == int(os.Stdin.Fd()) {
+		return readPasswordLine(os.Stdin)
+	}
+	if fd >= 0 {
+		file := os.NewFile(uintptr(fd), "password-fd")
+		if file == nil {
+			return nil, fmt.Errorf("invalid file descriptor: %d", fd)
+		}
+		defer file.Close()
+		return readPasswordLine(file)
+	}
+	if path := os.Getenv(passwordFileEnv); path != "" {
+		if stat, err := os.Stat(path); err == nil && stat.Mode().Perm()&0077 != 0 {
+			fmt.Fprintf(os.Stderr, "Warning: the master password file (%s) can be read by other users.\n", path)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the master password file (%s)", err.Error())
+		}
+		defer file.Close()
+		return readPasswordLine(file)
+	}
+
+	// when stdin is not a terminal (e.g. it's piped), the password is read from the controlling terminal
+	terminalFd := int(syscall.Stdin)
+	if !terminal.IsTerminal(terminalFd) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return nil, fmt.Errorf("no terminal to read the master password from, use -password-fd or %s",
+				passwordFileEnv)
+		}
+		defer tty.Close()
+		terminalFd = int(tty.Fd())
+	}
+	print("Please enter your master password: ")
+	pass, err := terminal.ReadPassword(terminalFd)
+	println("")
+	return pass, err
+}
+
+// readPasswordLine reads the first line of r, without reading anything after the line, so that r may be
+// read by others afterwards (e.g. if it's stdin).
+func readPasswordLine(r io.Reader) ([]byte, error) {
+	var pass []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			pass = append(pass, b[0])
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			encryption.Zero(pass)
+			return nil, fmt.Errorf("unable to read the master password (%s)", err.Error())
+		}
+	}
+	if len(pass) > 0 && pass[len(pass)-1] == '\r' {
+		pass[len(pass)-1] = 0
+		pass = pass[:len(pass)-1]
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("the master password is empty")
+	}
+	return pass, nil
+}
+
+// parseInterspersed parses flags which may be given before, after or between positional arguments,
+// returning the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	return flags
+}
+
+// parseEntryRef parses the arguments of subcommands taking a single entry reference, plus the given flags.
+func parseEntryRef(flags *flag.FlagSet, args []string) (string, error) {
+	positional, err := parseInterspersed(flags, args)
+	switch {
+	case err != nil:
+		return "", err
+	case len(positional) == 0:
+		return "", errors.New("please provide an entry")
+	case len(positional) > 1:
+		return "", fmt.Errorf("unexpected argument: '%s'", positional[1])
+	}
+	return positional[0], nil
+}
+
+// findSubcommandEntry finds the entry referred to by ref (see findEntry), starting from the default group,
+// returning its group and the entry, or nil and the exit status if it can't be found.
+func findSubcommandEntry(state *State, ref string) (string, *LoginInfo, int) {
+	group, index, err := findEntry(state, gohash_db.DefaultGroup, ref)
+	if err == gohash_db.ErrEntryNotFound {
+		return "", nil, subcommandError(exitNotFound, "entry '%s' does not exist", ref)
+	} else if err != nil {
+		return "", nil, subcommandError(exitError, "%s", err.Error())
+	}
+	return group, &(*state)[group][index], exitOK
+}
+
+// ============= Subcommands ============= //
+
+type getSubcommand struct {
+	ref   string
+	field string
+}
+
+func (cmd *getSubcommand) parse(args []string) (writes bool, err error) {
+	flags := newFlagSet("get")
+	flags.StringVar(&cmd.field, "f", "", "field")
+	if cmd.ref, err = parseEntryRef(flags, args); err != nil {
+		return
+	}
+	// HOTP counters are incremented every time a one-time password is generated
+	return cmd.field == "otp", nil
+}
+
+func (cmd *getSubcommand) run(state *State, reader *bufio.Reader, output outputOptions) (changed bool, status int) {
+	group, info, status := findSubcommandEntry(state, cmd.ref)
+	if info == nil {
+		return
+	}
+	var value string
+	switch cmd.field {
+	case "":
+		if output.format != gohash_db.TableFormat {
+			writeDocument(output.format, gohash_db.NewEntryDocument(group, info, output.withSecrets))
+			return
+		}
+		value = info.String()
+	case "otp":
+		if info.OTP == nil {
+			return false, subcommandError(exitNotFound, "entry '%s' does not have one-time passwords", info.Name)
+		}
+		now := time.Now()
+		code, _, err := info.OTP.Code(now)
+		if err != nil {
+			return false, subcommandError(exitError, "%s", err.Error())
+		}
+		if value, changed = code, info.OTP.Type == gohash_db.HOTP; changed {
+			info.UpdatedAt = now
+		}
+	default:
+		var ok bool
+		if value, ok = entryField(info, cmd.field); !ok {
+			return false, subcommandError(exitNotFound, "entry '%s' does not have a field called '%s'",
+				info.Name, cmd.field)
+		}
+	}
+	fmt.Println(value)
+	return
+}
+
+type lsSubcommand struct {
+	group string
+}
+
+func (cmd *lsSubcommand) parse(args []string) (writes bool, err error) {
+	positional, err := parseInterspersed(newFlagSet("ls"), args)
+	switch {
+	case err != nil:
+		return
+	case len(positional) > 1:
+		err = fmt.Errorf("unexpected argument: '%s'", positional[1])
+	case len(positional) == 1:
+		cmd.group, err = gohash_db.CleanGroupPath(strings.Trim(positional[0], gohash_db.GroupSeparator))
+	default:
+		cmd.group = gohash_db.DefaultGroup
+	}
+	return
+}
+
+func (cmd *lsSubcommand) run(state *State, reader *bufio.Reader, output outputOptions) (changed bool, status int) {
+	if !state.GroupExists(cmd.group) {
+		return false, subcommandError(exitNotFound, "group '%s' does not exist", cmd.group)
+	}
+	if output.format != gohash_db.TableFormat {
+		writeDocument(output.format, state.NewListing(cmd.group, output.withSecrets))
+		return
+	}
+	for _, subGroup := range state.SubGroups(cmd.group) {
+		if subGroup != gohash_db.DefaultGroup {
+			fmt.Println(gohash_db.GroupName(subGroup) + gohash_db.GroupSeparator)
+		}
+	}
+	entries := (*state)[cmd.group]
+	names := make([]string, len(entries))
+	for i := range entries {
+		names[i] = entries[i].Name
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return
+}
+
+type addSubcommand struct {
+	group       string
+	name        string
+	username    string
+	url         string
+	description string
+	generate    bool
+}
+
+func (cmd *addSubcommand) parse(args []string) (writes bool, err error) {
+	flags := newFlagSet("add")
+	flags.StringVar(&cmd.username, "u", "", "username")
+	flags.StringVar(&cmd.url, "url", "", "URL")
+	flags.StringVar(&cmd.description, "d", "", "description")
+	flags.BoolVar(&cmd.generate, "g", false, "generate password")
+	ref, err := parseEntryRef(flags, args)
+	if err != nil {
+		return
+	}
+	if isIDReference(ref) {
+		return true, errors.New("entry names cannot start with '#'")
+	}
+	cmd.group, cmd.name = gohash_db.DefaultGroup, ref
+	if i := strings.Index(ref, ":"); i >= 0 {
+		if cmd.group, err = gohash_db.CleanGroupPath(ref[:i]); err != nil {
+			return
+		}
+		cmd.name = ref[i+1:]
+	}
+	if len(strings.TrimSpace(cmd.name)) == 0 {
+		return true, errors.New("please provide the name of the entry")
+	}
+	if _, err = url.Parse(cmd.url); err != nil {
+		return true, fmt.Errorf("invalid URL: %s", err.Error())
+	}
+	return true, nil
+}
+
+func (cmd *addSubcommand) run(state *State, reader *bufio.Reader, output outputOptions) (changed bool, status int) {
+	if _, exists := state.FindEntry(cmd.group, cmd.name); exists {
+		return false, subcommandError(exitError, "entry '%s' already exists in group '%s'", cmd.name, cmd.group)
+	}
+	var password string
+	if cmd.generate {
+		password = encryption.GeneratePassword(16, encryption.DefaultPasswordCharRange())
+	} else {
+		if terminal.IsTerminal(int(syscall.Stdin)) {
+			print("Please enter a password for the entry: ")
+		}
+		pass, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, subcommandError(exitError, "unable to read the password (%s)", err.Error())
+		}
+		password = strings.TrimRight(pass, "\r\n")
+		if len(password) < 4 {
+			return false, subcommandError(exitError, "the password must have at least 4 characters")
+		}
+	}
+
+	state.AddGroup(cmd.group)
+	(*state)[cmd.group] = append((*state)[cmd.group], setEntryValues(cmd.name, nil, entryValues{
+		username: cmd.username, URL: cmd.url, description: cmd.description, password: password}, time.Now()))
+	return true, exitOK
+}
+
+type rmSubcommand struct {
+	ref string
+}
+
+func (cmd *rmSubcommand) parse(args []string) (writes bool, err error) {
+	cmd.ref, err = parseEntryRef(newFlagSet("rm"), args)
+	return true, err
+}
+
+func (cmd *rmSubcommand) run(state *State, reader *bufio.Reader, output outputOptions) (changed bool, status int) {
+	if _, info, status := findSubcommandEntry(state, cmd.ref); info == nil {
+		return false, status
+	}
+	if !(entryCommand{}).run(state, gohash_db.DefaultGroup, "-d "+cmd.ref, reader) {
+		return false, exitError
+	}
+	return true, exitOK
+}